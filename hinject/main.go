@@ -2,75 +2,293 @@ package main
 
 import (
 	"bufio"
+	"crypto/rand"
 	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/logrusorgru/aurora"
 )
 
-func main() {
+const injectHeader = "X-Forwarded-Host"
 
-	client := newClient()
-	sc := bufio.NewScanner(os.Stdin)
-	var wg sync.WaitGroup
-	var forwarded = "0a6d8cfc90fb8ef81240cd1f127409098dd846e1.local"
-	var verboseMode bool
+type result struct {
+	URL         string   `json:"url"`
+	Header      string   `json:"header"`
+	Canary      string   `json:"canary"`
+	Vulnerable  bool     `json:"vulnerable"`
+	Status      int      `json:"status"`
+	Reflections []string `json:"reflections,omitempty"`
+	Snippet     string   `json:"snippet,omitempty"`
+}
+
+type stats struct {
+	mu     sync.Mutex
+	errors map[string]int
+}
+
+func (s *stats) fail(kind string) {
+	s.mu.Lock()
+	s.errors[kind]++
+	s.mu.Unlock()
+}
+
+func (s *stats) summary() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var kinds []string
+	total := 0
+	for kind, n := range s.errors {
+		kinds = append(kinds, kind)
+		total += n
+	}
+	if total == 0 {
+		return ""
+	}
+	sort.Strings(kinds)
+
+	parts := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		parts = append(parts, fmt.Sprintf("%d %s", s.errors[kind], kind))
+	}
+	return fmt.Sprintf("%d errors (%s)", total, strings.Join(parts, ", "))
+}
+
+type hostLimiter struct {
+	mu    sync.Mutex
+	every time.Duration
+	next  map[string]time.Time
+}
+
+func newHostLimiter(rate int) *hostLimiter {
+	l := &hostLimiter{next: make(map[string]time.Time)}
+	if rate > 0 {
+		l.every = time.Second / time.Duration(rate)
+	}
+	return l
+}
+
+func (l *hostLimiter) wait(host string) {
+	if l.every <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[host] = at.Add(l.every)
+	l.mu.Unlock()
+
+	time.Sleep(time.Until(at))
+}
+
+func randomCanary() string {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "0a6d8cfc90fb8ef81240cd1f127409098dd846e1.local"
+	}
+	return hex.EncodeToString(b) + ".local"
+}
+
+func main() {
+	var forwarded string
+	var verboseMode, jsonMode bool
+	var concurrency, rate, timeout int
+	var maxBody int64
 	flag.BoolVar(&verboseMode, "v", false, "be verbose")
+	flag.BoolVar(&jsonMode, "json", false, "output results as JSON lines")
+	flag.StringVar(&forwarded, "canary", randomCanary(), "canary host to inject (random per run by default)")
+	flag.IntVar(&concurrency, "c", 20, "number of concurrent workers")
+	flag.IntVar(&rate, "rl", 0, "max requests per second per host (0 = unlimited)")
+	flag.IntVar(&timeout, "timeout", 10, "request timeout in seconds")
+	flag.Int64Var(&maxBody, "max-body", 1<<20, "max response body bytes to read")
 
 	flag.Parse()
 
-	for sc.Scan() {
-		rawURL := sc.Text()
-		wg.Add(1)
+	if strings.TrimSpace(forwarded) == "" {
+		// an empty canary is contained in every header and body
+		fmt.Fprintln(os.Stderr, "error: -canary can't be empty")
+		os.Exit(2)
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
 
-		go func() {
-			defer wg.Done()
+	client := newClient(time.Duration(timeout) * time.Second)
+	limiter := newHostLimiter(rate)
+	st := &stats{errors: make(map[string]int)}
+	var found int64
 
-			req, err := http.NewRequest("GET", rawURL, nil)
-			req.Header.Set("X-Forwarded-Host", forwarded)
-			if err != nil && verboseMode {
-				fmt.Printf("[  %s  ] %s\n", aurora.Red("FAILED").String(), rawURL)
-				return
-			}
+	urls := make(chan string)
+	var wg sync.WaitGroup
 
-			resp, err := client.Do(req)
-			if err != nil && verboseMode {
-				fmt.Printf("[  %s  ] %s\n", aurora.Red("FAILED").String(), rawURL)
-				return
-			}
-			defer resp.Body.Close()
-
-			data, _ := ioutil.ReadAll(resp.Body)
-			if strings.Contains(string(data), forwarded) {
-				fmt.Printf("[%s] %s\n", aurora.Green("VULNERABLE").String(), rawURL)
-			} else {
-				if verboseMode {
-					fmt.Printf("[ %s ] %s\n", aurora.Yellow("NOT VULN").String(), rawURL)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rawURL := range urls {
+				res, err := check(client, limiter, rawURL, forwarded, maxBody)
+				if err != nil {
+					st.fail(err.kind)
+					if verboseMode && !jsonMode {
+						fmt.Printf("[  %s  ] %s (%v)\n", aurora.Red("FAILED").String(), rawURL, err.err)
+					}
+					continue
 				}
+				if res.Vulnerable {
+					atomic.AddInt64(&found, 1)
+				}
+				report(res, jsonMode, verboseMode)
 			}
 		}()
 	}
 
+	sc := bufio.NewScanner(os.Stdin)
+	for sc.Scan() {
+		if rawURL := strings.TrimSpace(sc.Text()); rawURL != "" {
+			urls <- rawURL
+		}
+	}
+	close(urls)
+
 	wg.Wait()
+
+	if err := sc.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: reading input: %v\n", err)
+	}
+	if summary := st.summary(); summary != "" {
+		fmt.Fprintln(os.Stderr, summary)
+	}
+	if found > 0 {
+		os.Exit(1)
+	}
+}
+
+type checkError struct {
+	kind string
+	err  error
+}
+
+func report(res *result, jsonMode, verboseMode bool) {
+	if !res.Vulnerable && !verboseMode {
+		return
+	}
+
+	if jsonMode {
+		b, err := json.Marshal(res)
+		if err != nil {
+			return
+		}
+		fmt.Println(string(b))
+		return
+	}
+
+	if res.Vulnerable {
+		fmt.Printf("[%s] %s [%s]\n", aurora.Green("VULNERABLE").String(), res.URL, strings.Join(res.Reflections, ","))
+	} else {
+		fmt.Printf("[ %s ] %s\n", aurora.Yellow("NOT VULN").String(), res.URL)
+	}
+}
+
+func check(client *http.Client, limiter *hostLimiter, rawURL, forwarded string, maxBody int64) (*result, *checkError) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, &checkError{"invalid url", err}
+	}
+	if u.Host == "" {
+		return nil, &checkError{"invalid url", fmt.Errorf("missing host")}
+	}
+
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, &checkError{"invalid url", err}
+	}
+	req.Header.Set(injectHeader, forwarded)
+
+	limiter.wait(u.Host)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, &checkError{"request failed", err}
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	if err != nil {
+		return nil, &checkError{"read failed", err}
+	}
+
+	res := &result{
+		URL:    rawURL,
+		Header: injectHeader,
+		Canary: forwarded,
+		Status: resp.StatusCode,
+	}
+
+	var names []string
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range resp.Header[name] {
+			if strings.Contains(value, forwarded) {
+				res.Reflections = append(res.Reflections, "header:"+name)
+				break
+			}
+		}
+	}
+
+	body := string(data)
+	if i := strings.Index(body, forwarded); i >= 0 {
+		res.Reflections = append(res.Reflections, "body")
+		res.Snippet = snippet(body, i, len(forwarded))
+	}
+
+	res.Vulnerable = len(res.Reflections) > 0
+	return res, nil
+}
+
+func snippet(body string, at, n int) string {
+	const around = 60
+
+	start := at - around
+	if start < 0 {
+		start = 0
+	}
+	end := at + n + around
+	if end > len(body) {
+		end = len(body)
+	}
+	return strings.Join(strings.Fields(body[start:end]), " ")
 }
 
-func newClient() *http.Client {
+func newClient(timeout time.Duration) *http.Client {
 
 	tr := &http.Transport{
-		MaxIdleConns:    30,
-		IdleConnTimeout: time.Second,
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     30 * time.Second,
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
 		DialContext: (&net.Dialer{
-			Timeout:   time.Second * 10,
-			KeepAlive: time.Second,
+			Timeout:   timeout,
+			KeepAlive: 30 * time.Second,
 		}).DialContext,
 	}
 
@@ -81,7 +299,7 @@ func newClient() *http.Client {
 	return &http.Client{
 		Transport:     tr,
 		CheckRedirect: re,
-		Timeout:       time.Second * 10,
+		Timeout:       timeout,
 	}
 
 }
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/log"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+var artifactNames = []string{"dom", "console", "har", "headers", "cookies", "tech"}
+
+func parseArtifacts(list string) (map[string]bool, error) {
+	artifacts := make(map[string]bool)
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "":
+		case name == "all":
+			for _, n := range artifactNames {
+				artifacts[n] = true
+			}
+		default:
+			known := false
+			for _, n := range artifactNames {
+				known = known || n == name
+			}
+			if !known {
+				return nil, fmt.Errorf("unknown artifact %q (want %s or all)", name, strings.Join(artifactNames, ","))
+			}
+			artifacts[name] = true
+		}
+	}
+	return artifacts, nil
+}
+
+type consoleEntry struct {
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`
+	Level  string    `json:"level"`
+	Text   string    `json:"text"`
+	URL    string    `json:"url,omitempty"`
+	Line   int64     `json:"line,omitempty"`
+}
+
+type exchange struct {
+	started  time.Time
+	request  *network.Request
+	response *network.Response
+	size     float64
+	duration float64
+	err      string
+}
+
+// recorder collects console output and network traffic of a tab while it loads.
+type recorder struct {
+	mu        sync.Mutex
+	console   []consoleEntry
+	exchanges []*exchange
+	pending   map[network.RequestID]*exchange
+	starts    map[network.RequestID]time.Time
+
+	dom     string
+	cookies []*network.Cookie
+	globals []string
+}
+
+func newRecorder(ctx context.Context, artifacts map[string]bool) *recorder {
+	r := &recorder{
+		pending: make(map[network.RequestID]*exchange),
+		starts:  make(map[network.RequestID]time.Time),
+	}
+	if len(artifacts) == 0 {
+		return r
+	}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		switch e := ev.(type) {
+		case *runtime.EventConsoleAPICalled:
+			var args []string
+			for _, arg := range e.Args {
+				args = append(args, remoteText(arg))
+			}
+			r.console = append(r.console, consoleEntry{Time: timestamp(e.Timestamp), Source: "console", Level: string(e.Type), Text: strings.Join(args, " ")})
+		case *runtime.EventExceptionThrown:
+			d := e.ExceptionDetails
+			text := d.Text
+			if d.Exception != nil && d.Exception.Description != "" {
+				text = d.Exception.Description
+			}
+			r.console = append(r.console, consoleEntry{Time: timestamp(e.Timestamp), Source: "exception", Level: "error", Text: text, URL: d.URL, Line: d.LineNumber + 1})
+		case *log.EventEntryAdded:
+			entry := consoleEntry{Source: string(e.Entry.Source), Level: string(e.Entry.Level), Text: e.Entry.Text, URL: e.Entry.URL, Line: e.Entry.LineNumber}
+			if e.Entry.Timestamp != nil {
+				entry.Time = e.Entry.Timestamp.Time()
+			}
+			r.console = append(r.console, entry)
+
+		case *network.EventRequestWillBeSent:
+			// a redirect reuses the request id, so close the previous hop first
+			if prev := r.pending[e.RequestID]; prev != nil && e.RedirectResponse != nil {
+				prev.response = e.RedirectResponse
+				prev.duration = r.elapsed(e.RequestID, e.Timestamp)
+			}
+			x := &exchange{request: e.Request}
+			if e.WallTime != nil {
+				x.started = e.WallTime.Time()
+			}
+			if e.Timestamp != nil {
+				r.starts[e.RequestID] = e.Timestamp.Time()
+			}
+			r.pending[e.RequestID] = x
+			r.exchanges = append(r.exchanges, x)
+		case *network.EventResponseReceived:
+			if x := r.pending[e.RequestID]; x != nil {
+				x.response = e.Response
+			}
+		case *network.EventLoadingFinished:
+			if x := r.pending[e.RequestID]; x != nil {
+				x.size = e.EncodedDataLength
+				x.duration = r.elapsed(e.RequestID, e.Timestamp)
+			}
+		case *network.EventLoadingFailed:
+			if x := r.pending[e.RequestID]; x != nil {
+				x.err = e.ErrorText
+				x.duration = r.elapsed(e.RequestID, e.Timestamp)
+			}
+		}
+	})
+	return r
+}
+
+// elapsed is the time in milliseconds since the request was sent.
+func (r *recorder) elapsed(id network.RequestID, t *cdp.MonotonicTime) float64 {
+	start, ok := r.starts[id]
+	if !ok || t == nil {
+		return 0
+	}
+	return float64(t.Time().Sub(start)) / float64(time.Millisecond)
+}
+
+// techProbe reports front-end libraries that announce themselves through globals.
+const techProbe = `(() => {
+	const found = [];
+	const add = (name, version) => found.push(version ? name + " " + version : name);
+	if (window.jQuery) add("jQuery", window.jQuery.fn && window.jQuery.fn.jquery);
+	if (window.React || document.querySelector("[data-reactroot]")) add("React", window.React && window.React.version);
+	if (window.Vue) add("Vue.js", window.Vue.version);
+	if (document.querySelector("[data-v-app]") || document.querySelector("[data-server-rendered]")) add("Vue.js");
+	if (window.angular) add("AngularJS", window.angular.version && window.angular.version.full);
+	const ng = document.querySelector("[ng-version]");
+	if (ng) add("Angular", ng.getAttribute("ng-version"));
+	if (window.__NEXT_DATA__) add("Next.js");
+	if (window.__NUXT__) add("Nuxt.js");
+	if (window.___gatsby) add("Gatsby");
+	if (window.Shopify) add("Shopify");
+	if (window.wp) add("WordPress");
+	if (window.Drupal) add("Drupal");
+	if (window.Ember) add("Ember.js", window.Ember.VERSION);
+	if (window.Backbone) add("Backbone.js", window.Backbone.VERSION);
+	if (window._ && window._.VERSION) add("Lodash", window._.VERSION);
+	if (window.bootstrap || (window.jQuery && window.jQuery.fn && window.jQuery.fn.modal)) add("Bootstrap");
+	if (window.gtag || window.ga || window.dataLayer) add("Google Analytics");
+	if (window.Sentry) add("Sentry");
+	if (window.Stripe) add("Stripe");
+	if (window.grecaptcha) add("reCAPTCHA");
+	return found;
+})()`
+
+// collect reads what has to come from the live page: the rendered DOM, the
+// cookie jar and the JS globals used for technology detection.
+func (r *recorder) collect(ctx context.Context, artifacts map[string]bool) error {
+	var tasks chromedp.Tasks
+	if artifacts["dom"] || artifacts["tech"] {
+		// the document node serializes XML and SVG documents too, which have no <html> to query
+		tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
+			root, err := dom.GetDocument().Do(ctx)
+			if err != nil {
+				return err
+			}
+			r.dom, err = dom.GetOuterHTML().WithNodeID(root.NodeID).Do(ctx)
+			return err
+		}))
+	}
+	if artifacts["cookies"] || artifacts["tech"] {
+		tasks = append(tasks, chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			r.cookies, err = network.GetCookies().Do(ctx)
+			return err
+		}))
+	}
+	if artifacts["tech"] {
+		tasks = append(tasks, chromedp.Evaluate(techProbe, &r.globals))
+	}
+	return chromedp.Run(ctx, tasks)
+}
+
+var (
+	generatorRe = regexp.MustCompile(`(?i)<meta[^>]+name=["']generator["'][^>]+content=["']([^"']+)["']`)
+	htmlTech    = map[string]string{
+		"/wp-content/": "WordPress", "/wp-includes/": "WordPress", "/_next/static/": "Next.js",
+		"/_nuxt/": "Nuxt.js", "cdn.shopify.com": "Shopify", "/sites/default/files/": "Drupal",
+		"joomla": "Joomla", "__VIEWSTATE": "ASP.NET", "cloudflare-static": "Cloudflare",
+		"static.wixstatic.com": "Wix", "squarespace.com": "Squarespace",
+	}
+	cookieTech = map[string]string{
+		"PHPSESSID": "PHP", "JSESSIONID": "Java", "ASP.NET_SessionId": "ASP.NET",
+		"laravel_session": "Laravel", "csrftoken": "Django",
+		"_rails_session": "Ruby on Rails", "connect.sid": "Express", "__cf_bm": "Cloudflare",
+		"AWSALB": "AWS ELB", "ci_session": "CodeIgniter",
+	}
+	headerTech = []string{"Server", "X-Powered-By", "X-AspNet-Version", "X-Generator", "Via", "X-Drupal-Cache", "X-Shopify-Stage"}
+)
+
+func (r *recorder) technologies(doc *network.Response) []string {
+	found := make(map[string]bool)
+	if doc != nil {
+		for _, name := range headerTech {
+			if v := headerValue(doc.Headers, name); v != "" {
+				found[name+": "+v] = true
+			}
+		}
+	}
+	for _, c := range r.cookies {
+		if tech, ok := cookieTech[c.Name]; ok {
+			found[tech] = true
+		}
+	}
+	for marker, tech := range htmlTech {
+		if strings.Contains(r.dom, marker) {
+			found[tech] = true
+		}
+	}
+	if m := generatorRe.FindStringSubmatch(r.dom); m != nil {
+		found[m[1]] = true
+	}
+	for _, g := range r.globals {
+		found[g] = true
+	}
+
+	var techs []string
+	for t := range found {
+		techs = append(techs, t)
+	}
+	sort.Strings(techs)
+	return techs
+}
+
+// save writes the selected artifacts next to the screenshot, named base plus a suffix.
+func (r *recorder) save(base string, artifacts map[string]bool, doc *network.Response) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if artifacts["dom"] {
+		if err := os.WriteFile(base+".html", []byte(r.dom), 0644); err != nil {
+			return err
+		}
+	}
+	if artifacts["console"] {
+		if err := writeJSON(base+".console.json", r.console); err != nil {
+			return err
+		}
+	}
+	if artifacts["har"] {
+		if err := writeJSON(base+".har", r.har()); err != nil {
+			return err
+		}
+	}
+	if artifacts["headers"] && doc != nil {
+		if err := writeJSON(base+".headers.json", doc.Headers); err != nil {
+			return err
+		}
+	}
+	if artifacts["cookies"] {
+		if err := writeJSON(base+".cookies.json", r.cookies); err != nil {
+			return err
+		}
+	}
+	if artifacts["tech"] {
+		if err := writeJSON(base+".tech.json", r.technologies(doc)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// har builds a HAR 1.2 log. Bodies aren't recorded and timings only split
+// out the wait for headers, so per-phase numbers are approximate.
+func (r *recorder) har() map[string]interface{} {
+	entries := []map[string]interface{}{}
+	for _, x := range r.exchanges {
+		if x.request == nil {
+			continue
+		}
+		response := map[string]interface{}{
+			"status": 0, "statusText": "", "httpVersion": "", "headers": []harNameValue{},
+			"cookies": []harNameValue{}, "redirectURL": "", "headersSize": -1, "bodySize": -1,
+			"content": map[string]interface{}{"size": 0, "mimeType": ""},
+		}
+		wait := x.duration
+		if resp := x.response; resp != nil {
+			response["status"] = resp.Status
+			response["statusText"] = resp.StatusText
+			response["httpVersion"] = resp.Protocol
+			response["headers"] = harHeaders(resp.Headers)
+			response["redirectURL"] = headerValue(resp.Headers, "Location")
+			response["bodySize"] = x.size
+			response["content"] = map[string]interface{}{"size": x.size, "mimeType": resp.MimeType}
+			if t := resp.Timing; t != nil && t.ReceiveHeadersEnd > t.SendEnd {
+				wait = t.ReceiveHeadersEnd - t.SendEnd
+			}
+		}
+		if x.err != "" {
+			response["_error"] = x.err
+		}
+
+		var query []harNameValue
+		if i := strings.Index(x.request.URL, "?"); i >= 0 {
+			for _, pair := range strings.Split(x.request.URL[i+1:], "&") {
+				name, value, _ := strings.Cut(pair, "=")
+				query = append(query, harNameValue{name, value})
+			}
+		}
+		if query == nil {
+			query = []harNameValue{}
+		}
+
+		receive := x.duration - wait
+		if receive < 0 {
+			receive = 0
+		}
+		entries = append(entries, map[string]interface{}{
+			"startedDateTime": x.started.Format(time.RFC3339Nano),
+			"time":            x.duration,
+			"request": map[string]interface{}{
+				"method": x.request.Method, "url": x.request.URL, "httpVersion": "",
+				"headers": harHeaders(x.request.Headers), "queryString": query,
+				"cookies": []harNameValue{}, "headersSize": -1, "bodySize": -1,
+			},
+			"response": response,
+			"cache":    map[string]interface{}{},
+			"timings":  map[string]interface{}{"send": 0, "wait": wait, "receive": receive},
+		})
+	}
+	return map[string]interface{}{
+		"log": map[string]interface{}{
+			"version": "1.2",
+			"creator": map[string]string{"name": "klik", "version": "1.0"},
+			"pages":   []interface{}{},
+			"entries": entries,
+		},
+	}
+}
+
+func harHeaders(headers network.Headers) []harNameValue {
+	list := []harNameValue{}
+	for k, v := range headers {
+		list = append(list, harNameValue{k, fmt.Sprint(v)})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func remoteText(obj *runtime.RemoteObject) string {
+	if len(obj.Value) > 0 {
+		var s string
+		if json.Unmarshal(obj.Value, &s) == nil {
+			return s
+		}
+		return string(obj.Value)
+	}
+	if obj.UnserializableValue != "" {
+		return string(obj.UnserializableValue)
+	}
+	if obj.Description != "" {
+		return obj.Description
+	}
+	return string(obj.Type)
+}
+
+func timestamp(t *runtime.Timestamp) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.Time()
+}
+
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
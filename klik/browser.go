@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/chromedp/chromedp"
+	"github.com/sirupsen/logrus"
+)
+
+// instance is one running Chrome process. Tabs are opened on ctx.
+type instance struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	pages  int
+	active int
+}
+
+// browser hands out tabs on a long-lived Chrome, replacing it after
+// cfg.recycle pages or when it crashed. A retired instance stays up until
+// its last open tab is released.
+type browser struct {
+	mu      sync.Mutex
+	cfg     *Config
+	current *instance
+}
+
+func newBrowsers(cfg *Config) []*browser {
+	browsers := make([]*browser, cfg.browsers)
+	for i := range browsers {
+		browsers[i] = &browser{cfg: cfg}
+	}
+	return browsers
+}
+
+func (b *browser) launch() (*instance, error) {
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), allocatorOptions(b.cfg)...)
+	ctx, cancel := chromedp.NewContext(allocCtx)
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		allocCancel()
+		return nil, err
+	}
+	return &instance{ctx: ctx, cancel: func() {
+		cancel()
+		allocCancel()
+	}}, nil
+}
+
+func (b *browser) acquire() (*instance, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if inst := b.current; inst != nil {
+		crashed := inst.ctx.Err() != nil
+		if crashed || (b.cfg.recycle > 0 && inst.pages >= b.cfg.recycle) {
+			if crashed {
+				logrus.Warn("browser crashed, restarting")
+			}
+			b.current = nil
+			if inst.active == 0 {
+				inst.cancel()
+			}
+		}
+	}
+
+	if b.current == nil {
+		inst, err := b.launch()
+		if err != nil {
+			return nil, err
+		}
+		b.current = inst
+	}
+	b.current.pages++
+	b.current.active++
+	return b.current, nil
+}
+
+func (b *browser) release(inst *instance) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	inst.active--
+	if inst != b.current && inst.active == 0 {
+		inst.cancel()
+	}
+}
+
+// tab opens a new tab with the per-target timeout and runs fn in it. Jobs
+// that fail because the whole browser went away are retried once on a fresh one.
+func (b *browser) tab(fn func(ctx context.Context) error) error {
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var inst *instance
+		if inst, err = b.acquire(); err != nil {
+			return err
+		}
+
+		ctx, cancel := chromedp.NewContext(inst.ctx)
+		ctx, cancelTimeout := context.WithTimeout(ctx, time.Duration(b.cfg.timeout)*time.Second)
+		err = fn(ctx)
+		cancelTimeout()
+		cancel()
+
+		crashed := inst.ctx.Err() != nil
+		b.release(inst)
+		if err == nil || !crashed {
+			return err
+		}
+	}
+	return err
+}
+
+func (b *browser) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.current != nil {
+		b.current.cancel()
+		b.current = nil
+	}
+}
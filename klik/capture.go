@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+type device struct {
+	width     int64
+	height    int64
+	scale     float64
+	mobile    bool
+	userAgent string
+}
+
+var devices = map[string]device{
+	"desktop": {width: 1440, height: 900, scale: 1},
+	"tablet": {width: 820, height: 1180, scale: 2, mobile: true,
+		userAgent: "Mozilla/5.0 (iPad; CPU OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1"},
+	"mobile": {width: 390, height: 844, scale: 3, mobile: true,
+		userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1"},
+}
+
+var formats = map[string]page.CaptureScreenshotFormat{
+	"png":  page.CaptureScreenshotFormatPng,
+	"jpeg": page.CaptureScreenshotFormatJpeg,
+	"webp": page.CaptureScreenshotFormatWebp,
+}
+
+func validateCapture(cfg *Config) error {
+	if _, ok := devices[cfg.device]; !ok {
+		return fmt.Errorf("unknown device %q (want desktop, tablet or mobile)", cfg.device)
+	}
+	if _, ok := formats[cfg.format]; !ok {
+		return fmt.Errorf("unknown format %q (want png, jpeg or webp)", cfg.format)
+	}
+	if cfg.mode != "full" && cfg.mode != "viewport" {
+		return fmt.Errorf("unknown mode %q (want full or viewport)", cfg.mode)
+	}
+	if cfg.quality < 0 || cfg.quality > 100 {
+		return fmt.Errorf("quality must be between 0 and 100")
+	}
+	return nil
+}
+
+func extension(cfg *Config) string {
+	if cfg.format == "jpeg" {
+		return ".jpg"
+	}
+	return "." + cfg.format
+}
+
+// emulate sets the viewport, pixel ratio and user agent of the device preset
+// before navigating. -ua wins over the preset's user agent.
+func emulate(cfg *Config) chromedp.Tasks {
+	d := devices[cfg.device]
+	tasks := chromedp.Tasks{
+		emulation.SetDeviceMetricsOverride(d.width, d.height, d.scale, d.mobile),
+	}
+	if d.mobile {
+		tasks = append(tasks, emulation.SetTouchEmulationEnabled(true))
+	}
+	userAgent := d.userAgent
+	if cfg.userAgent != "" {
+		userAgent = cfg.userAgent
+	}
+	if userAgent != "" {
+		tasks = append(tasks, emulation.SetUserAgentOverride(userAgent))
+	}
+	return tasks
+}
+
+// capture screenshots the viewport, or the whole page up to maxHeight CSS
+// pixels without resizing the viewport, so responsive layouts stay intact.
+func capture(cfg *Config, buf *[]byte) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		shot := page.CaptureScreenshot().WithFormat(formats[cfg.format])
+		if cfg.format != "png" {
+			shot = shot.WithQuality(int64(cfg.quality))
+		}
+
+		if cfg.mode == "full" {
+			_, _, _, _, _, contentSize, err := page.GetLayoutMetrics().Do(ctx)
+			if err != nil {
+				return err
+			}
+
+			height := contentSize.Height
+			if cfg.maxHeight > 0 {
+				height = math.Min(height, float64(cfg.maxHeight))
+			}
+			shot = shot.WithCaptureBeyondViewport(true).
+				WithClip(&page.Viewport{
+					X: contentSize.X, Y: contentSize.Y,
+					Width: math.Ceil(contentSize.Width), Height: math.Ceil(height), Scale: 1,
+				})
+		}
+
+		var err error
+		*buf, err = shot.Do(ctx)
+		return err
+	})
+}
+
+// hashCapture takes a small jpeg of the viewport when the output format is
+// webp, which the standard library can't decode for perceptual hashing.
+func hashCapture(cfg *Config, buf *[]byte) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		if cfg.format != "webp" {
+			return nil
+		}
+		var err error
+		*buf, err = page.CaptureScreenshot().WithFormat(page.CaptureScreenshotFormatJpeg).WithQuality(50).Do(ctx)
+		return err
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// documents records the document responses of a tab by URL. Redirect hops
+// don't produce responses here, so the final URL maps to the final response.
+type documents struct {
+	mu    sync.Mutex
+	first *network.Response
+	byURL map[string]*network.Response
+}
+
+func listenDocuments(ctx context.Context) *documents {
+	d := &documents{byURL: make(map[string]*network.Response)}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		e, ok := ev.(*network.EventResponseReceived)
+		if !ok || e.Type != network.ResourceTypeDocument {
+			return
+		}
+		d.mu.Lock()
+		if d.first == nil {
+			d.first = e.Response
+		}
+		d.byURL[e.Response.URL] = e.Response
+		d.mu.Unlock()
+	})
+	return d
+}
+
+func (d *documents) get(url string) *network.Response {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if resp, ok := d.byURL[url]; ok {
+		return resp
+	}
+	return d.first
+}
+
+func headerValue(headers network.Headers, name string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			if s, ok := v.(string); ok {
+				return s
+			}
+		}
+	}
+	return ""
+}
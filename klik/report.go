@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+type result struct {
+	URL      string
+	FinalURL string
+	Status   int64
+	Title    string
+	Server   string
+	File     string
+	Error    string
+	hash     uint64
+	hashed   bool
+}
+
+type cluster struct {
+	Results []*result
+}
+
+type report struct {
+	mu      sync.Mutex
+	results []*result
+}
+
+func (r *report) add(res *result) {
+	r.mu.Lock()
+	r.results = append(r.results, res)
+	r.mu.Unlock()
+}
+
+// perceptualHash is a 64-bit DCT hash of the top square of the image, so
+// full-page captures of different lengths still compare by what's above the fold.
+func perceptualHash(data []byte) (uint64, bool) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, false
+	}
+
+	const size = 32
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if height > width {
+		height = width
+	}
+	if width == 0 || height == 0 {
+		return 0, false
+	}
+
+	var pixels [size][size]float64
+	for y := 0; y < size; y++ {
+		y0, y1 := span(b.Min.Y, y, height, size)
+		for x := 0; x < size; x++ {
+			x0, x1 := span(b.Min.X, x, width, size)
+			var sum float64
+			var n int
+			for sy := y0; sy < y1; sy += step(y0, y1) {
+				for sx := x0; sx < x1; sx += step(x0, x1) {
+					r, g, bl, _ := img.At(sx, sy).RGBA()
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)
+					n++
+				}
+			}
+			pixels[y][x] = sum / float64(n)
+		}
+	}
+
+	var coeffs []float64
+	for v := 0; v < 8; v++ {
+		for u := 0; u < 8; u++ {
+			var sum float64
+			for y := 0; y < size; y++ {
+				for x := 0; x < size; x++ {
+					sum += pixels[y][x] *
+						math.Cos(float64(2*x+1)*float64(u)*math.Pi/(2*size)) *
+						math.Cos(float64(2*y+1)*float64(v)*math.Pi/(2*size))
+				}
+			}
+			coeffs = append(coeffs, sum)
+		}
+	}
+
+	// the DC term only reflects overall brightness
+	sorted := append([]float64(nil), coeffs[1:]...)
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+
+	var hash uint64
+	for i, c := range coeffs {
+		if i > 0 && c > median {
+			hash |= 1 << uint(i)
+		}
+	}
+	return hash, true
+}
+
+// span is the source pixel range of cell i when length pixels are split into n cells.
+func span(origin, i, length, n int) (int, int) {
+	from, to := origin+i*length/n, origin+(i+1)*length/n
+	if to <= from {
+		to = from + 1
+	}
+	return from, to
+}
+
+// step samples about four pixels per axis of a cell, which is plenty for a 32x32 average.
+func step(from, to int) int {
+	if s := (to - from) / 4; s > 1 {
+		return s
+	}
+	return 1
+}
+
+// clusters groups screenshots whose hashes are within threshold bits of a
+// cluster's first member, largest cluster first.
+func clusters(results []*result, threshold int) []cluster {
+	var groups []cluster
+	for _, res := range results {
+		if res.Error != "" {
+			continue
+		}
+		placed := false
+		for i := range groups {
+			first := groups[i].Results[0]
+			if res.hashed && first.hashed && bits.OnesCount64(res.hash^first.hash) <= threshold {
+				groups[i].Results = append(groups[i].Results, res)
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			groups = append(groups, cluster{Results: []*result{res}})
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].Results) > len(groups[j].Results)
+	})
+	return groups
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>klik report</title>
+<style>
+body { font-family: sans-serif; background: #111; color: #ddd; margin: 2em; }
+a { color: #8cf; }
+h2 { border-bottom: 1px solid #333; padding-bottom: .3em; }
+.grid { display: flex; flex-wrap: wrap; gap: 1em; }
+.card { background: #1b1b1b; width: 320px; padding: .5em; word-break: break-all; font-size: 13px; }
+.card img { width: 100%; max-height: 240px; object-fit: cover; object-position: top; }
+.status { font-weight: bold; }
+table { border-collapse: collapse; font-size: 13px; }
+td { border: 1px solid #333; padding: .3em .6em; }
+</style>
+</head>
+<body>
+<h1>klik report</h1>
+<p>{{len .Results}} targets, {{len .Clusters}} visual clusters</p>
+{{range $i, $c := .Clusters}}
+<h2>cluster {{$i}} ({{len $c.Results}})</h2>
+<div class="grid">
+{{range $c.Results}}
+<div class="card">
+<a href="{{.File}}"><img src="{{.File}}" loading="lazy"></a>
+<div><a href="{{.URL}}">{{.URL}}</a></div>
+{{if and .FinalURL (ne .FinalURL .URL)}}<div>→ <a href="{{.FinalURL}}">{{.FinalURL}}</a></div>{{end}}
+<div><span class="status">{{if .Status}}{{.Status}}{{else}}-{{end}}</span> {{.Server}}</div>
+<div>{{.Title}}</div>
+</div>
+{{end}}
+</div>
+{{end}}
+{{if .Failed}}
+<h2>failed ({{len .Failed}})</h2>
+<table>
+{{range .Failed}}<tr><td>{{.URL}}</td><td>{{.Error}}</td></tr>
+{{end}}
+</table>
+{{end}}
+</body>
+</html>
+`))
+
+func (r *report) write(cfg *Config) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sort.Slice(r.results, func(i, j int) bool { return r.results[i].URL < r.results[j].URL })
+	var failed []*result
+	for _, res := range r.results {
+		if res.Error != "" {
+			failed = append(failed, res)
+		}
+	}
+
+	f, err := os.Create(filepath.Join(cfg.output, cfg.report))
+	if err != nil {
+		return err
+	}
+	err = reportTemplate.Execute(f, map[string]interface{}{
+		"Results":  r.results,
+		"Clusters": clusters(r.results, cfg.cluster),
+		"Failed":   failed,
+	})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
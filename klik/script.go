@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// command is one line of an action script: a command and its arguments.
+type command struct {
+	line int
+	cmd  string
+	args []string
+}
+
+var commandArgs = map[string]int{
+	"click": 1, "type": 2, "scroll": 1, "waitfor": 1, "sleep": 1,
+	"cookie": 1, "localstorage": 1, "reload": 0,
+}
+
+// loadScript parses an action script. Each line is a command followed by its
+// arguments; arguments with spaces go in double quotes.
+//
+//	click "#accept-cookies"
+//	type input[name=user] admin
+//	scroll bottom
+//	cookie session=abc123
+//	localstorage token=abc123
+//	reload
+func loadScript(path string) ([]command, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var steps []command
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields, err := splitArgs(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		cmd := strings.ToLower(fields[0])
+		want, ok := commandArgs[cmd]
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: unknown command %q", path, n, fields[0])
+		}
+		if len(fields)-1 != want {
+			return nil, fmt.Errorf("%s:%d: %s takes %d argument(s)", path, n, cmd, want)
+		}
+		if cmd == "sleep" {
+			if _, err := time.ParseDuration(fields[1]); err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+			}
+		}
+		if (cmd == "cookie" || cmd == "localstorage") && !strings.Contains(fields[1], "=") {
+			return nil, fmt.Errorf("%s:%d: %s wants name=value", path, n, cmd)
+		}
+		steps = append(steps, command{line: n, cmd: cmd, args: fields[1:]})
+	}
+	return steps, scanner.Err()
+}
+
+func splitArgs(line string) ([]string, error) {
+	var fields []string
+	for line = strings.TrimSpace(line); line != ""; line = strings.TrimSpace(line) {
+		if line[0] != '"' {
+			end := strings.IndexAny(line, " \t")
+			if end < 0 {
+				end = len(line)
+			}
+			fields = append(fields, line[:end])
+			line = line[end:]
+			continue
+		}
+		quoted, err := strconv.QuotedPrefix(line)
+		if err != nil {
+			return nil, fmt.Errorf("unterminated quote")
+		}
+		value, _ := strconv.Unquote(quoted)
+		fields = append(fields, value)
+		line = line[len(quoted):]
+	}
+	return fields, nil
+}
+
+func (s command) action() chromedp.Action {
+	switch s.cmd {
+	case "click":
+		return chromedp.Click(s.args[0], chromedp.ByQuery)
+	case "type":
+		return chromedp.SendKeys(s.args[0], s.args[1], chromedp.ByQuery)
+	case "waitfor":
+		return chromedp.WaitVisible(s.args[0], chromedp.ByQuery)
+	case "sleep":
+		d, _ := time.ParseDuration(s.args[0])
+		return chromedp.Sleep(d)
+	case "reload":
+		return chromedp.Reload()
+	case "scroll":
+		switch s.args[0] {
+		case "top":
+			return chromedp.Evaluate(`window.scrollTo(0, 0)`, nil)
+		case "bottom":
+			return chromedp.Evaluate(`window.scrollTo(0, document.documentElement.scrollHeight)`, nil)
+		}
+		if px, err := strconv.Atoi(s.args[0]); err == nil {
+			return chromedp.Evaluate(fmt.Sprintf(`window.scrollBy(0, %d)`, px), nil)
+		}
+		return chromedp.ScrollIntoView(s.args[0], chromedp.ByQuery)
+	case "cookie":
+		name, value, _ := strings.Cut(s.args[0], "=")
+		return chromedp.ActionFunc(func(ctx context.Context) error {
+			var location string
+			if err := chromedp.Location(&location).Do(ctx); err != nil {
+				return err
+			}
+			return network.SetCookie(name, value).WithURL(location).Do(ctx)
+		})
+	case "localstorage":
+		key, value, _ := strings.Cut(s.args[0], "=")
+		k, _ := json.Marshal(key)
+		v, _ := json.Marshal(value)
+		return chromedp.Evaluate(fmt.Sprintf(`localStorage.setItem(%s, %s)`, k, v), nil)
+	}
+	return chromedp.Tasks{}
+}
+
+// script wraps each step so a failure names the script line.
+func script(steps []command) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		for _, s := range steps {
+			if err := s.action().Do(ctx); err != nil {
+				return fmt.Errorf("script line %d (%s): %v", s.line, s.cmd, err)
+			}
+		}
+		return nil
+	})
+}
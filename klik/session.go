@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/security"
+	"github.com/chromedp/chromedp"
+)
+
+// headerFlags collects repeated -H "Name: value" flags.
+type headerFlags network.Headers
+
+func (h headerFlags) String() string {
+	var pairs []string
+	for name, value := range h {
+		pairs = append(pairs, fmt.Sprintf("%s: %v", name, value))
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func (h headerFlags) Set(v string) error {
+	name, value, ok := strings.Cut(v, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return fmt.Errorf("header %q isn't name: value", v)
+	}
+	h[name] = strings.TrimSpace(value)
+	return nil
+}
+
+// parseProxy splits credentials off a proxy URL, since Chrome ignores them in
+// --proxy-server and has to be answered on the auth challenge instead.
+func parseProxy(raw string) (string, *url.Userinfo, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks4", "socks5":
+	default:
+		return "", nil, fmt.Errorf("unknown proxy scheme %q (want http, https, socks4 or socks5)", u.Scheme)
+	}
+	if u.Host == "" {
+		return "", nil, fmt.Errorf("proxy %q has no host", raw)
+	}
+	if u.User != nil && u.Scheme != "http" && u.Scheme != "https" {
+		return "", nil, fmt.Errorf("chrome only authenticates to http proxies")
+	}
+	return u.Scheme + "://" + u.Host, u.User, nil
+}
+
+// loadCookies reads a cookie jar, either the JSON klik writes with -save
+// cookies or a Netscape cookies.txt as exported by curl and browser extensions.
+func loadCookies(path string) ([]*network.CookieParam, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		var jar []*network.Cookie
+		if err := json.Unmarshal(data, &jar); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		var cookies []*network.CookieParam
+		for _, c := range jar {
+			cookies = append(cookies, cookieParam(c.Name, c.Value, c.Domain, c.Path, c.Secure, c.HTTPOnly, c.Expires, c.SameSite))
+		}
+		return cookies, nil
+	}
+
+	var cookies []*network.CookieParam
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		httpOnly := strings.HasPrefix(line, "#HttpOnly_")
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("%s:%d: want 7 tab separated fields, got %d", path, n, len(fields))
+		}
+		expires, err := strconv.ParseFloat(fields[4], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: bad expiry %q", path, n, fields[4])
+		}
+		if expires == 0 {
+			expires = -1
+		}
+		cookies = append(cookies, cookieParam(fields[5], fields[6], fields[0], fields[2], strings.EqualFold(fields[3], "TRUE"), httpOnly, expires, ""))
+	}
+	return cookies, scanner.Err()
+}
+
+// cookieParam converts a stored cookie, where an expiry <= 0 means a session cookie.
+func cookieParam(name, value, domain, path string, secure, httpOnly bool, expires float64, sameSite network.CookieSameSite) *network.CookieParam {
+	c := &network.CookieParam{
+		Name: name, Value: value, Domain: domain, Path: path,
+		Secure: secure, HTTPOnly: httpOnly, SameSite: sameSite,
+	}
+	if c.Path == "" {
+		c.Path = "/"
+	}
+	if expires > 0 {
+		sec, frac := int64(expires), expires-float64(int64(expires))
+		t := cdp.TimeSinceEpoch(time.Unix(sec, int64(frac*1e9)))
+		c.Expires = &t
+	}
+	return c
+}
+
+// allocatorOptions are the Chrome flags for a new browser.
+func allocatorOptions(cfg *Config) []chromedp.ExecAllocatorOption {
+	opts := append([]chromedp.ExecAllocatorOption(nil), chromedp.DefaultExecAllocatorOptions[:]...)
+	if cfg.proxy != "" {
+		opts = append(opts, chromedp.ProxyServer(cfg.proxy))
+	}
+	if cfg.insecure {
+		opts = append(opts, chromedp.IgnoreCertErrors)
+	}
+	if cfg.userAgent != "" {
+		opts = append(opts, chromedp.UserAgent(cfg.userAgent))
+	}
+	return opts
+}
+
+// session applies headers, cookies, certificate errors and proxy credentials
+// to a tab. It must be called before the tab runs its first action so the
+// auth listener is in place.
+func session(ctx context.Context, cfg *Config) chromedp.Tasks {
+	var tasks chromedp.Tasks
+	if len(cfg.headers) > 0 {
+		tasks = append(tasks, network.SetExtraHTTPHeaders(network.Headers(cfg.headers)))
+	}
+	if len(cfg.cookies) > 0 {
+		tasks = append(tasks, network.SetCookies(cfg.cookies))
+	}
+	if cfg.insecure {
+		tasks = append(tasks, security.SetIgnoreCertificateErrors(true))
+	}
+	if cfg.proxyAuth != nil {
+		answerProxyAuth(ctx, cfg.proxyAuth)
+		tasks = append(tasks, fetch.Enable().WithHandleAuthRequests(true))
+	}
+	return tasks
+}
+
+// answerProxyAuth lets every paused request through and answers proxy
+// challenges with the credentials. Replies run off the event loop, which
+// must not block on a command.
+func answerProxyAuth(ctx context.Context, user *url.Userinfo) {
+	password, _ := user.Password()
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		var action chromedp.Action
+		switch e := ev.(type) {
+		case *fetch.EventRequestPaused:
+			action = fetch.ContinueRequest(e.RequestID)
+		case *fetch.EventAuthRequired:
+			answer := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseDefault}
+			if e.AuthChallenge.Source == fetch.AuthChallengeSourceProxy {
+				answer = &fetch.AuthChallengeResponse{
+					Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+					Username: user.Username(),
+					Password: password,
+				}
+			}
+			action = fetch.ContinueWithAuth(e.RequestID, answer)
+		default:
+			return
+		}
+		go func() {
+			c := chromedp.FromContext(ctx)
+			action.Do(cdp.WithExecutor(ctx, c.Target))
+		}()
+	})
+}
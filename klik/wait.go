@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// idleTracker counts in-flight requests of a tab to detect network idle.
+type idleTracker struct {
+	mu       sync.Mutex
+	inflight map[network.RequestID]bool
+	last     time.Time
+}
+
+func trackIdle(ctx context.Context) *idleTracker {
+	t := &idleTracker{inflight: make(map[network.RequestID]bool), last: time.Now()}
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		switch e := ev.(type) {
+		case *network.EventRequestWillBeSent:
+			t.inflight[e.RequestID] = true
+		case *network.EventLoadingFinished:
+			delete(t.inflight, e.RequestID)
+		case *network.EventLoadingFailed:
+			delete(t.inflight, e.RequestID)
+		default:
+			return
+		}
+		t.last = time.Now()
+	})
+	return t
+}
+
+// wait blocks until no request was in flight for quiet. Pages that never go
+// quiet (polling, websockets) are given up on after limit and captured anyway.
+func (t *idleTracker) wait(quiet, limit time.Duration) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+		deadline := time.Now().Add(limit)
+		for {
+			t.mu.Lock()
+			idle := len(t.inflight) == 0 && time.Since(t.last) >= quiet
+			t.mu.Unlock()
+			if idle || time.Now().After(deadline) {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// waits runs the configured wait conditions in order: network idle, the
+// selector, the JS expression and finally the fixed delay.
+func waits(cfg *Config, idle *idleTracker) chromedp.Tasks {
+	var tasks chromedp.Tasks
+	if cfg.idle > 0 {
+		tasks = append(tasks, idle.wait(cfg.idle, time.Duration(cfg.timeout)*time.Second/2))
+	}
+	if cfg.waitFor != "" {
+		tasks = append(tasks, chromedp.WaitVisible(cfg.waitFor, chromedp.ByQuery))
+	}
+	if cfg.waitJS != "" {
+		var ok bool
+		tasks = append(tasks, chromedp.Poll(cfg.waitJS, &ok, chromedp.WithPollingInterval(100*time.Millisecond), chromedp.WithPollingTimeout(0)))
+	}
+	if cfg.delay > 0 {
+		tasks = append(tasks, chromedp.Sleep(cfg.delay))
+	}
+	return tasks
+}
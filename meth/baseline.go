@@ -0,0 +1,156 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const calibrationProbes = 3
+
+type fingerprint struct {
+	status   int
+	size     int
+	length   int
+	hash     string
+	bodyless bool
+}
+
+type baseline struct {
+	name         string
+	fingerprints []fingerprint
+	dynamic      bool
+	raw          []rawResponse
+}
+
+// rawResponse is a baseline response kept as received, for baselines that are
+// fingerprinted again with different method names stripped.
+type rawResponse struct {
+	status int
+	body   []byte
+}
+
+type baselineEntry struct {
+	once sync.Once
+	b    *baseline
+}
+
+var (
+	baselinesMu sync.Mutex
+	baselines   = make(map[string]*baselineEntry)
+)
+
+func junkMethod() string {
+	const letters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	b := make([]byte, 6+rand.Intn(5))
+	for i := range b {
+		b[i] = letters[rand.Intn(len(letters))]
+	}
+	return string(b)
+}
+
+// takeFingerprint reads the response body and strips the method names from it,
+// so servers that echo the method in their error page still compare equal.
+func takeFingerprint(resp *http.Response, methods ...string) fingerprint {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	return newFingerprint(resp.StatusCode, data, resp.Request.Method == "HEAD", methods...)
+}
+
+func newFingerprint(status int, data []byte, head bool, methods ...string) fingerprint {
+	body := string(data)
+	for _, method := range methods {
+		body = strings.ReplaceAll(body, method, "")
+	}
+	sum := sha1.Sum([]byte(body))
+	return fingerprint{
+		status:   status,
+		size:     len(data),
+		length:   len(body),
+		hash:     hex.EncodeToString(sum[:]),
+		bodyless: head || status == 204 || status == 304,
+	}
+}
+
+func cachedBaseline(key string, build func() *baseline) *baseline {
+	baselinesMu.Lock()
+	entry, ok := baselines[key]
+	if !ok {
+		entry = &baselineEntry{}
+		baselines[key] = entry
+	}
+	baselinesMu.Unlock()
+
+	entry.once.Do(func() {
+		entry.b = build()
+	})
+	return entry.b
+}
+
+func getBaseline(client *http.Client, url string) *baseline {
+	return cachedBaseline(url, func() *baseline {
+		return calibrate(client, url)
+	})
+}
+
+func calibrate(client *http.Client, url string) *baseline {
+	b := &baseline{name: "baseline"}
+	for i := 0; i < calibrationProbes; i++ {
+		method := junkMethod()
+		req, err := http.NewRequest(method, url, nil)
+		if err != nil {
+			return nil
+		}
+		req.Header.Set("User-Agent", "meth/1.0")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			continue
+		}
+		b.fingerprints = append(b.fingerprints, takeFingerprint(resp, method))
+		resp.Body.Close()
+	}
+	b.detectDynamic()
+
+	if len(b.fingerprints) == 0 {
+		logf("%s[CALIBRATION]%s %s - no baseline, falling back to status codes\n", Yellow, Reset, url)
+		return nil
+	}
+	return b
+}
+
+func (b *baseline) detectDynamic() {
+	for i, a := range b.fingerprints {
+		for _, c := range b.fingerprints[i+1:] {
+			if a.status == c.status && a.hash != c.hash {
+				b.dynamic = true
+			}
+		}
+	}
+}
+
+// differs reports whether fp deviates from every baseline response, and why.
+// Baselines whose junk responses disagree with each other, and responses that
+// can't carry a body (HEAD, 204, 304), are only compared by status.
+func (b *baseline) differs(fp fingerprint) (bool, string) {
+	var reason string
+	for _, base := range b.fingerprints {
+		switch {
+		case fp.status != base.status:
+			reason = fmt.Sprintf("status %d, %s %d", fp.status, b.name, base.status)
+		case b.dynamic, fp.bodyless, base.bodyless:
+			return false, "matches " + b.name + " status"
+		case fp.hash != base.hash && fp.length != base.length:
+			reason = fmt.Sprintf("length %d, %s %d", fp.length, b.name, base.length)
+		case fp.hash != base.hash:
+			reason = "body differs from " + b.name
+		default:
+			return false, "matches " + b.name
+		}
+	}
+	return true, reason
+}
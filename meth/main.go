@@ -8,10 +8,9 @@ import (
 	"os"
 	"strings"
 	"sync"
-	"time"
 )
 
-const (
+var (
 	Red    = "\033[31m"
 	Yellow = "\033[33m"
 	Green  = "\033[32m"
@@ -22,59 +21,93 @@ const (
 	Reset  = "\033[0m"
 )
 
+var client *http.Client
+
 var (
 	target      = flag.String("u", "", "Target URL")
 	exclude     = flag.String("x", "", "Exclude methods (comma-separated)")
 	include     = flag.String("i", "", "Include only these methods (comma-separated)")
 	custom      = flag.String("c", "", "Add custom methods (comma-separated)")
 	threads     = flag.Int("t", 10, "Number of threads")
+	perHost     = flag.Int("ph", 5, "Max concurrent requests per host (0 = unlimited)")
+	rateLimit   = flag.Int("rl", 0, "Max requests per second across all hosts (0 = unlimited)")
 	timeout     = flag.Int("timeout", 10, "Timeout in seconds")
 	verbose     = flag.Bool("v", false, "Verbose output")
 	silent      = flag.Bool("s", false, "Silent mode (only show working methods)")
+	jsonOut     = flag.Bool("json", false, "Output results as JSON lines")
+	csvOut      = flag.Bool("csv", false, "Output results as CSV")
+	noColor     = flag.Bool("no-color", false, "Disable colored output")
+	override    = flag.Bool("override", false, "Test methods through override headers and _method parameters on POST")
+	noCalibrate = flag.Bool("nc", false, "Disable baseline calibration against junk methods")
+	verify      = flag.Bool("verify", false, "Verify impact of PUT, DELETE, MKCOL, PROPFIND and TRACE (writes and removes canary files)")
 )
 
 func main() {
-	showBanner()
 	flag.Parse()
+	if *verify && *override {
+		fmt.Fprintf(os.Stderr, "Error: -verify can't be combined with -override, override probes are never verified\n")
+		os.Exit(1)
+	}
+	setupOutput()
+	if !*silent && !*jsonOut && !*csvOut {
+		showBanner()
+	}
+
+	methods := getHTTPMethods()
+	methods = filterMethods(methods)
+
+	if *threads < 1 {
+		*threads = 1
+	}
+	client = newClient()
+
+	type job struct {
+		url, method string
+	}
+	jobs := make(chan job, *threads)
+	var wg sync.WaitGroup
+
+	for i := 0; i < *threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if *override {
+					checkOverride(j.url, j.method)
+				} else {
+					checkMethod(j.url, j.method)
+				}
+			}
+		}()
+	}
+
+	count := 0
+	produce := func(url string) {
+		count++
+		for _, method := range methods {
+			jobs <- job{url, method}
+		}
+	}
 
-	var urls []string
-	
 	if *target != "" {
-		urls = append(urls, *target)
+		produce(*target)
 	} else {
 		scanner := bufio.NewScanner(os.Stdin)
 		for scanner.Scan() {
 			url := strings.TrimSpace(scanner.Text())
 			if url != "" {
-				urls = append(urls, url)
+				produce(url)
 			}
 		}
 	}
+	close(jobs)
+
+	wg.Wait()
 
-	if len(urls) == 0 {
+	if count == 0 {
 		fmt.Fprintf(os.Stderr, "Error: No URLs provided. Use -u flag or pipe URLs via stdin\n")
 		os.Exit(1)
 	}
-
-	methods := getHTTPMethods()
-	methods = filterMethods(methods)
-
-	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, *threads)
-	
-	for _, url := range urls {
-		for _, method := range methods {
-			wg.Add(1)
-			go func(url, method string) {
-				defer wg.Done()
-				semaphore <- struct{}{}
-				checkMethod(url, method)
-				<-semaphore
-			}(url, method)
-		}
-	}
-	
-	wg.Wait()
 }
 
 func showBanner() {
@@ -133,18 +166,9 @@ func filterMethods(methods []string) []string {
 }
 
 func checkMethod(url, method string) {
-	client := &http.Client{
-		Timeout: time.Duration(*timeout) * time.Second,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
-	}
-
 	req, err := http.NewRequest(method, url, nil)
 	if err != nil {
-		if *verbose {
-			fmt.Printf("%s[ERROR]%s %s - %s: %v\n", Red, Reset, method, url, err)
-		}
+		report(result{URL: url, Method: method, Verdict: verdictError, Reason: err.Error()})
 		return
 	}
 
@@ -152,23 +176,39 @@ func checkMethod(url, method string) {
 	
 	resp, err := client.Do(req)
 	if err != nil {
-		if *verbose {
-			fmt.Printf("%s[TIMEOUT]%s %s - %s\n", Yellow, Reset, method, url)
-		}
+		report(result{URL: url, Method: method, Verdict: verdictError, Reason: err.Error()})
 		return
 	}
-	defer resp.Body.Close()
+	fp := takeFingerprint(resp, method)
+	resp.Body.Close()
+	r := result{
+		URL:     url,
+		Method:  method,
+		Status:  resp.StatusCode,
+		Length:  fp.size,
+		Allow:   resp.Header.Get("Allow"),
+		Verdict: verdictDenied,
+	}
 
-	if isMethodAllowed(resp.StatusCode, method, resp.Header.Get("Allow")) {
-		statusColor := getStatusColor(resp.StatusCode)
-		methodColor := getMethodColor(method)
-		fmt.Printf("%s[%s%d%s%s]%s%s[%s%s%s%s]%s %s\n", 
-			White, statusColor, resp.StatusCode, Reset, White, Reset,
-			White, methodColor, method, Reset, White, Reset, url)
-	} else if *verbose {
-		statusColor := getStatusColor(resp.StatusCode)
-		fmt.Printf("%s[%s%d%s%s]%s %s - %s\n", White, statusColor, resp.StatusCode, Reset, White, Reset, method, url)
+	allowed := isMethodAllowed(resp.StatusCode, method, r.Allow)
+	if allowed && !*noCalibrate {
+		if b := getBaseline(client, url); b != nil {
+			allowed, r.Reason = b.differs(fp)
+		}
 	}
+
+	if allowed {
+		r.Verdict = verdictAllowed
+		if v, ok := verifiers[method]; ok && *verify {
+			verified, detail := v(client, url)
+			r.Verdict, r.Reason = verdictVerified, detail
+			if !verified {
+				r.Verdict = verdictUnverified
+			}
+		}
+	}
+
+	report(r)
 }
 
 func isMethodAllowed(statusCode int, method string, allowHeader string) bool {
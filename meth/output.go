@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+)
+
+const (
+	verdictAllowed    = "allowed"
+	verdictVerified   = "verified"
+	verdictUnverified = "unverified"
+	verdictDenied     = "denied"
+	verdictError      = "error"
+)
+
+type result struct {
+	URL     string `json:"url"`
+	Method  string `json:"method"`
+	Vector  string `json:"vector,omitempty"`
+	Status  int    `json:"status"`
+	Length  int    `json:"length"`
+	Allow   string `json:"allow"`
+	Verdict string `json:"verdict"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+func (r result) isFinding() bool {
+	return r.Verdict == verdictAllowed || r.Verdict == verdictVerified
+}
+
+var (
+	outMu     sync.Mutex
+	csvWriter *csv.Writer
+)
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func disableColors() {
+	Red, Yellow, Green, Blue, Purple, Cyan, White, Reset = "", "", "", "", "", "", "", ""
+}
+
+func setupOutput() {
+	if *jsonOut && *csvOut {
+		fmt.Fprintf(os.Stderr, "Error: -json and -csv are mutually exclusive\n")
+		os.Exit(1)
+	}
+	if *noColor || *jsonOut || *csvOut || !isTerminal(os.Stdout) {
+		disableColors()
+	}
+	if *csvOut {
+		csvWriter = csv.NewWriter(os.Stdout)
+		csvWriter.Write([]string{"url", "method", "vector", "status", "length", "allow", "verdict", "reason"})
+		csvWriter.Flush()
+	}
+}
+
+// logf prints diagnostics to stderr so structured output on stdout stays clean.
+func logf(format string, args ...interface{}) {
+	if *verbose && !*silent {
+		fmt.Fprintf(os.Stderr, format, args...)
+	}
+}
+
+func report(r result) {
+	if !r.isFinding() && (!*verbose || *silent) {
+		return
+	}
+
+	outMu.Lock()
+	defer outMu.Unlock()
+
+	switch {
+	case *jsonOut:
+		b, err := json.Marshal(r)
+		if err == nil {
+			fmt.Println(string(b))
+		}
+	case *csvOut:
+		csvWriter.Write([]string{r.URL, r.Method, r.Vector, strconv.Itoa(r.Status), strconv.Itoa(r.Length), r.Allow, r.Verdict, r.Reason})
+		csvWriter.Flush()
+	default:
+		printText(r)
+	}
+}
+
+func printText(r result) {
+	method := r.Method
+	if r.Vector != "" {
+		method += " via " + r.Vector
+	}
+	statusColor := getStatusColor(r.Status)
+	methodColor := getMethodColor(r.Method)
+
+	switch r.Verdict {
+	case verdictError:
+		fmt.Printf("%s[ERROR]%s %s - %s: %s\n", Red, Reset, method, r.URL, r.Reason)
+	case verdictVerified:
+		fmt.Printf("%s[%s%d%s%s]%s%s[%s%s%s%s]%s %s %s[VERIFIED]%s %s\n",
+			White, statusColor, r.Status, Reset, White, Reset,
+			White, methodColor, method, Reset, White, Reset, r.URL, Green, Reset, r.Reason)
+	case verdictAllowed:
+		fmt.Printf("%s[%s%d%s%s]%s%s[%s%s%s%s]%s %s%s\n",
+			White, statusColor, r.Status, Reset, White, Reset,
+			White, methodColor, method, Reset, White, Reset, r.URL, formatReason(r.Reason))
+	case verdictUnverified:
+		fmt.Printf("%s[%s%d%s%s]%s %s - %s (unverified: %s)\n", White, statusColor, r.Status, Reset, White, Reset, method, r.URL, r.Reason)
+	default:
+		fmt.Printf("%s[%s%d%s%s]%s %s - %s%s\n", White, statusColor, r.Status, Reset, White, Reset, method, r.URL, formatReason(r.Reason))
+	}
+}
+
+func formatReason(reason string) string {
+	if reason == "" {
+		return ""
+	}
+	return " (" + reason + ")"
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const formType = "application/x-www-form-urlencoded"
+
+type overrideVector struct {
+	name  string
+	build func(target, method string) (*http.Request, error)
+}
+
+var overrideVectors = []overrideVector{
+	{"X-HTTP-Method-Override", headerOverride("X-HTTP-Method-Override")},
+	{"X-HTTP-Method", headerOverride("X-HTTP-Method")},
+	{"X-Method-Override", headerOverride("X-Method-Override")},
+	{"_method query", queryOverride},
+	{"_method body", bodyOverride},
+}
+
+func headerOverride(header string) func(target, method string) (*http.Request, error) {
+	return func(target, method string) (*http.Request, error) {
+		req, err := plainPost(target, "")
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(header, method)
+		return req, nil
+	}
+}
+
+func queryOverride(target, method string) (*http.Request, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("_method", method)
+	u.RawQuery = q.Encode()
+	return plainPost(u.String(), "")
+}
+
+func bodyOverride(target, method string) (*http.Request, error) {
+	return plainPost(target, url.Values{"_method": {method}}.Encode())
+}
+
+func plainPost(target, body string) (*http.Request, error) {
+	req, err := http.NewRequest("POST", target, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "meth/1.0")
+	req.Header.Set("Content-Type", formType)
+	return req, nil
+}
+
+// getPostBaseline sends the plain POSTs once per target and keeps their raw
+// bodies, see stripped.
+func getPostBaseline(client *http.Client, target string) *baseline {
+	return cachedBaseline("POST "+target, func() *baseline {
+		b := &baseline{name: "plain POST"}
+		for i := 0; i < 2; i++ {
+			req, err := plainPost(target, "")
+			if err != nil {
+				return nil
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				continue
+			}
+			data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+			resp.Body.Close()
+			b.raw = append(b.raw, rawResponse{resp.StatusCode, data})
+		}
+		if len(b.raw) == 0 {
+			return nil
+		}
+		return b
+	})
+}
+
+// stripped fingerprints the plain POSTs with the same method names stripped
+// as the override probes for method, so a server echoing the real POST in
+// its error page doesn't look like it honoured the override.
+func (b *baseline) stripped(method string) *baseline {
+	s := &baseline{name: b.name}
+	for _, r := range b.raw {
+		s.fingerprints = append(s.fingerprints, newFingerprint(r.status, r.body, false, "POST", method))
+	}
+	s.detectDynamic()
+	return s
+}
+
+func checkOverride(target, method string) {
+	if method == "POST" {
+		return
+	}
+
+	base := getPostBaseline(client, target)
+	if base == nil {
+		report(result{URL: target, Method: "POST", Verdict: verdictError, Reason: "no response to plain POST"})
+		return
+	}
+	base = base.stripped(method)
+
+	for _, vector := range overrideVectors {
+		req, err := vector.build(target, method)
+		if err != nil {
+			report(result{URL: target, Method: method, Vector: vector.name, Verdict: verdictError, Reason: err.Error()})
+			continue
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			report(result{URL: target, Method: method, Vector: vector.name, Verdict: verdictError, Reason: err.Error()})
+			continue
+		}
+		fp := takeFingerprint(resp, "POST", method)
+		resp.Body.Close()
+
+		r := result{
+			URL:     target,
+			Method:  method,
+			Vector:  vector.name,
+			Status:  resp.StatusCode,
+			Length:  fp.size,
+			Allow:   resp.Header.Get("Allow"),
+			Verdict: verdictDenied,
+		}
+		var differs bool
+		differs, r.Reason = base.differs(fp)
+		if differs {
+			r.Verdict = verdictAllowed
+		}
+		report(r)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// limitedTransport caps in-flight requests per host and paces all requests
+// globally, so calibration and verification traffic obeys the same limits.
+// The timeout starts once a request got its turn, so time spent queued
+// behind the limits never counts against it.
+type limitedTransport struct {
+	base    http.RoundTripper
+	perHost int
+	tick    <-chan time.Time
+	timeout time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+}
+
+func (t *limitedTransport) hostSlot(host string) chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sem, ok := t.hosts[host]
+	if !ok {
+		sem = make(chan struct{}, t.perHost)
+		t.hosts[host] = sem
+	}
+	return sem
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.tick != nil {
+		select {
+		case <-t.tick:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	release := func() {}
+	if t.perHost > 0 {
+		sem := t.hostSlot(req.URL.Host)
+		select {
+		case sem <- struct{}{}:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		release = func() { <-sem }
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		release()
+		return nil, err
+	}
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: func() {
+		cancel()
+		release()
+	}}
+	return resp, nil
+}
+
+type releaseBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+func newClient() *http.Client {
+	base := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		MaxIdleConns:        *threads * 2,
+		MaxIdleConnsPerHost: *perHost,
+		MaxConnsPerHost:     *perHost,
+		IdleConnTimeout:     30 * time.Second,
+		TLSHandshakeTimeout: time.Duration(*timeout) * time.Second,
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+		DialContext: (&net.Dialer{
+			Timeout:   time.Duration(*timeout) * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+	}
+
+	tr := &limitedTransport{
+		base:    base,
+		perHost: *perHost,
+		timeout: time.Duration(*timeout) * time.Second,
+		hosts:   make(map[string]chan struct{}),
+	}
+	if *rateLimit > 0 {
+		// one request per nanosecond is the finest a ticker can pace
+		interval := time.Second / time.Duration(*rateLimit)
+		if interval < 1 {
+			interval = 1
+		}
+		tr.tick = time.NewTicker(interval).C
+	}
+
+	return &http.Client{
+		Transport: tr,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type verifier func(client *http.Client, target string) (bool, string)
+
+var verifiers = map[string]verifier{
+	"PUT":      verifyPut,
+	"DELETE":   verifyDelete,
+	"MKCOL":    verifyMkcol,
+	"PROPFIND": verifyPropfind,
+	"TRACE": func(client *http.Client, target string) (bool, string) {
+		return verifyEcho(client, "TRACE", target)
+	},
+	"TRACK": func(client *http.Client, target string) (bool, string) {
+		return verifyEcho(client, "TRACK", target)
+	},
+}
+
+type multistatus struct {
+	XMLName   xml.Name `xml:"DAV: multistatus"`
+	Responses []struct {
+		Href string `xml:"DAV: href"`
+	} `xml:"DAV: response"`
+}
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?><propfind xmlns="DAV:"><prop><resourcetype/></prop></propfind>`
+
+func randomToken() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func resolve(target, name string) (string, error) {
+	base, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(name)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+func send(client *http.Client, method, target string, body []byte, headers map[string]string) (*http.Response, []byte, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, target, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", "meth/1.0")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	return resp, data, err
+}
+
+// putCanary uploads a random file next to target and returns its url and content.
+func putCanary(client *http.Client, target string) (string, string, error) {
+	token := randomToken()
+	canary, err := resolve(target, "meth-"+token+".txt")
+	if err != nil {
+		return "", "", err
+	}
+	content := "meth-canary-" + token
+
+	resp, _, err := send(client, "PUT", canary, []byte(content), map[string]string{"Content-Type": "text/plain"})
+	if err != nil {
+		return "", "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", "", fmt.Errorf("PUT returned %d", resp.StatusCode)
+	}
+	return canary, content, nil
+}
+
+// fetchContains GETs target and reports its status and whether a 200 body
+// holds content. A failed request is returned as an error, not as "missing".
+func fetchContains(client *http.Client, target, content string) (int, bool, error) {
+	resp, data, err := send(client, "GET", target, nil, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	return resp.StatusCode, resp.StatusCode == 200 && strings.Contains(string(data), content), nil
+}
+
+func cleanup(client *http.Client, target string) {
+	send(client, "DELETE", target, nil, nil)
+}
+
+func verifyPut(client *http.Client, target string) (bool, string) {
+	canary, content, err := putCanary(client, target)
+	if err != nil {
+		return false, err.Error()
+	}
+	defer cleanup(client, canary)
+
+	if _, found, err := fetchContains(client, canary, content); err != nil {
+		return false, "read back failed: " + err.Error()
+	} else if !found {
+		return false, "uploaded file not readable at " + canary
+	}
+	return true, "uploaded and read back " + canary
+}
+
+func verifyDelete(client *http.Client, target string) (bool, string) {
+	canary, content, err := putCanary(client, target)
+	if err != nil {
+		return false, "no canary to delete: " + err.Error()
+	}
+	if _, found, err := fetchContains(client, canary, content); err != nil || !found {
+		cleanup(client, canary)
+		return false, "no canary to delete: uploaded file not readable"
+	}
+
+	resp, _, err := send(client, "DELETE", canary, nil, nil)
+	if err != nil {
+		return false, err.Error()
+	}
+	status, found, err := fetchContains(client, canary, content)
+	switch {
+	case err != nil:
+		return false, "re-GET after DELETE failed: " + err.Error()
+	case found:
+		return false, fmt.Sprintf("DELETE returned %d but %s still exists", resp.StatusCode, canary)
+	case status == 404, status == 410, status == 200:
+		return true, "deleted uploaded canary " + canary
+	}
+	return false, fmt.Sprintf("DELETE returned %d, re-GET returned %d", resp.StatusCode, status)
+}
+
+func verifyMkcol(client *http.Client, target string) (bool, string) {
+	collection, err := resolve(target, "meth-"+randomToken()+"/")
+	if err != nil {
+		return false, err.Error()
+	}
+
+	resp, _, err := send(client, "MKCOL", collection, nil, nil)
+	if err != nil {
+		return false, err.Error()
+	}
+	if resp.StatusCode != 201 {
+		return false, fmt.Sprintf("MKCOL returned %d", resp.StatusCode)
+	}
+	defer cleanup(client, collection)
+
+	n, err := propfind(client, collection, "0")
+	if err != nil {
+		return false, "collection not listed: " + err.Error()
+	}
+	return true, fmt.Sprintf("created collection %s (%d multistatus entries)", collection, n)
+}
+
+func verifyPropfind(client *http.Client, target string) (bool, string) {
+	n, err := propfind(client, target, "1")
+	if err != nil {
+		return false, err.Error()
+	}
+	return true, fmt.Sprintf("multistatus with %d entries", n)
+}
+
+func propfind(client *http.Client, target, depth string) (int, error) {
+	resp, data, err := send(client, "PROPFIND", target, []byte(propfindBody), map[string]string{
+		"Depth":        depth,
+		"Content-Type": "application/xml",
+	})
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != 207 {
+		return 0, fmt.Errorf("PROPFIND returned %d", resp.StatusCode)
+	}
+
+	var ms multistatus
+	if err := xml.Unmarshal(data, &ms); err != nil {
+		return 0, fmt.Errorf("invalid multistatus: %v", err)
+	}
+	if len(ms.Responses) == 0 {
+		return 0, fmt.Errorf("empty multistatus")
+	}
+	return len(ms.Responses), nil
+}
+
+func verifyEcho(client *http.Client, method, target string) (bool, string) {
+	token := randomToken()
+	resp, data, err := send(client, method, target, nil, map[string]string{"X-Meth-Canary": token})
+	if err != nil {
+		return false, err.Error()
+	}
+	if resp.StatusCode != 200 || !strings.Contains(string(data), token) {
+		return false, fmt.Sprintf("request headers not echoed (%d)", resp.StatusCode)
+	}
+	return true, "request headers echoed in response body"
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	_ "embed"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+//go:embed confusables.txt
+var confusablesData string
+
+type variant struct {
+	text  string
+	score float64
+}
+
+var (
+	prototypes = make(map[rune]string)
+	lookalikes = make(map[string][]variant)
+)
+
+func init() {
+	for _, line := range strings.Split(confusablesData, "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Split(line, ";")
+		if len(fields) < 2 {
+			continue
+		}
+		source := parseCodepoints(fields[0])
+		target := parseCodepoints(fields[1])
+		if len([]rune(source)) != 1 || target == "" {
+			continue
+		}
+		prototypes[[]rune(source)[0]] = target
+	}
+
+	seen := make(map[string]bool)
+	add := func(text string) {
+		if seen[text] {
+			return
+		}
+		seen[text] = true
+		key := skeleton(text)
+		lookalikes[key] = append(lookalikes[key], variant{text, similarity(text)})
+	}
+	for r, target := range prototypes {
+		add(string(r))
+		add(target)
+	}
+	for key := range lookalikes {
+		vs := lookalikes[key]
+		sort.Slice(vs, func(i, j int) bool {
+			if vs[i].score != vs[j].score {
+				return vs[i].score > vs[j].score
+			}
+			return vs[i].text < vs[j].text
+		})
+	}
+}
+
+func parseCodepoints(field string) string {
+	var b strings.Builder
+	for _, hex := range strings.Fields(field) {
+		cp, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return ""
+		}
+		b.WriteRune(rune(cp))
+	}
+	return b.String()
+}
+
+// skeleton is the UTS #39 skeleton: strings with equal skeletons are confusable.
+func skeleton(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if p, ok := prototypes[r]; ok {
+			b.WriteString(p)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return norm.NFD.String(b.String())
+}
+
+// similarity scores a single substitution. Plain lookalikes score 1.0,
+// compatibility forms (fullwidth, mathematical, ...) render styled and score
+// 0.9, and multi-character prototypes like "rn" for "m" score 0.8.
+func similarity(text string) float64 {
+	switch {
+	case len([]rune(text)) > 1:
+		return 0.8
+	case norm.NFKC.String(text) != text:
+		return 0.9
+	}
+	return 1.0
+}
+
+// variantsFor returns every lookalike of r according to the confusables data.
+func variantsFor(r rune) []variant {
+	var result []variant
+	for _, v := range lookalikes[skeleton(string(r))] {
+		if v.text != string(r) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// registrable reports whether s passes IDNA 2008 / UTS-46 registration rules unchanged.
+// x/net/idna still accepts the symbols UTS-46 marks NV8, so those are rejected
+// here using the RFC 5892 letter/digit categories.
+func registrable(s string) bool {
+	for _, r := range s {
+		if r != '-' && r != '.' && !unicode.In(r, unicode.Ll, unicode.Lo, unicode.Lm, unicode.Mn, unicode.Mc, unicode.Nd) {
+			return false
+		}
+	}
+	if _, err := idna.Registration.ToASCII(s); err != nil {
+		return false
+	}
+	u, err := idna.Registration.ToUnicode(s)
+	return err == nil && u == s
+}
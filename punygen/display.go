@@ -0,0 +1,192 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// recommendedScripts are the UAX #31 recommended scripts. Chromium's spoof
+// checker only allows characters from these (plus Common and Inherited).
+var recommendedScripts = []string{
+	"Latin", "Arabic", "Armenian", "Bengali", "Bopomofo", "Cyrillic", "Devanagari",
+	"Ethiopic", "Georgian", "Greek", "Gujarati", "Gurmukhi", "Han", "Hangul", "Hebrew",
+	"Hiragana", "Kannada", "Katakana", "Khmer", "Lao", "Malayalam", "Myanmar", "Oriya",
+	"Sinhala", "Tamil", "Telugu", "Thaana", "Thai", "Tibetan",
+}
+
+// highlyRestrictive lists the script mixes the ICU "highly restrictive" level allows besides a single script.
+var highlyRestrictive = [][]string{
+	{"Latin", "Han", "Hiragana", "Katakana"},
+	{"Latin", "Han", "Bopomofo"},
+	{"Latin", "Han", "Hangul"},
+}
+
+// wholeScriptTLDs are the TLDs where a label written entirely in Latin
+// lookalikes of that script is expected and still shown as Unicode.
+var wholeScriptTLDs = map[string][]string{
+	"Armenian": {"am"},
+	"Cyrillic": {"bg", "by", "kz", "pyc", "ru", "su", "ua", "uz", "бг", "бел", "қаз", "мкд", "мон", "рф", "рус", "срб", "укр"},
+	"Ethiopic": {"er", "et"},
+	"Georgian": {"ge"},
+	"Greek":    {"gr", "ελ"},
+	"Hebrew":   {"il"},
+	"Thai":     {"th", "ไทย"},
+}
+
+// restrictedRanges holds blocks whose characters UTS #39 marks technical,
+// obsolete or uncommon (IPA, phonetic extensions, modifier letters, ...) and
+// characters Chromium blocks outright.
+var restrictedRanges = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x01bf, Hi: 0x01bf, Stride: 1},
+		{Lo: 0x01c0, Hi: 0x01c3, Stride: 1},
+		{Lo: 0x0250, Hi: 0x02ff, Stride: 1},
+		{Lo: 0x0337, Hi: 0x0338, Stride: 1},
+		{Lo: 0x04c0, Hi: 0x04c0, Stride: 1},
+		{Lo: 0x04cf, Hi: 0x04cf, Stride: 1},
+		{Lo: 0x05f4, Hi: 0x05f4, Stride: 1},
+		{Lo: 0x06d4, Hi: 0x06d4, Stride: 1},
+		{Lo: 0x0e3a, Hi: 0x0e3a, Stride: 1},
+		{Lo: 0x1d00, Hi: 0x1dbf, Stride: 1},
+		{Lo: 0x3007, Hi: 0x3007, Stride: 1},
+		{Lo: 0xa720, Hi: 0xa7ff, Stride: 1},
+		{Lo: 0xab30, Hi: 0xab6f, Stride: 1},
+	},
+}
+
+var deviations = map[rune]bool{'ß': true, 'ς': true, '\u200c': true, '\u200d': true}
+
+type display struct {
+	unicode bool
+	reason  string
+}
+
+func (d display) String() string {
+	if d.unicode {
+		return "unicode"
+	}
+	return "xn-- (" + d.reason + ")"
+}
+
+// displayPolicy approximates Chromium's IDN spoof checks for a domain: it
+// reports whether the address bar would show it in Unicode or fall back to
+// punycode. Chromium's top-domain skeleton check isn't modelled.
+func displayPolicy(domain string) display {
+	labels := strings.Split(domain, ".")
+	tld := ""
+	if len(labels) > 1 {
+		tld = labels[len(labels)-1]
+	}
+	for _, label := range labels {
+		if reason := checkLabel(label, tld); reason != "" {
+			return display{false, reason}
+		}
+	}
+	return display{true, ""}
+}
+
+func checkLabel(label, tld string) string {
+	scripts := make(map[string]bool)
+	zero := rune(-1)
+	for _, r := range label {
+		switch {
+		case r < 0x80:
+			if unicode.IsLetter(r) {
+				scripts["Latin"] = true
+			}
+		case r == 'þ' && tld != "is":
+			return "restricted"
+		case deviations[r]:
+			return "deviation"
+		case unicode.Is(restrictedRanges, r):
+			return "restricted"
+		case unicode.IsDigit(r) && unicode.Is(unicode.Common, r):
+			// non-ASCII digits belong to their script via script extensions, which Go doesn't expose
+			scripts["Digits"] = true
+		case !unicode.In(r, unicode.Common, unicode.Inherited):
+			script := scriptOf(r)
+			if script == "" {
+				return "restricted"
+			}
+			scripts[script] = true
+		}
+		if unicode.IsDigit(r) {
+			z := digitZero(r)
+			if zero >= 0 && z != zero {
+				return "mixed-numbers"
+			}
+			zero = z
+		}
+	}
+
+	if !allowedMix(scripts) {
+		return "mixed-script"
+	}
+	if len(scripts) == 1 && !scripts["Latin"] && !scripts["Digits"] {
+		for script := range scripts {
+			if wholeScriptConfusable(label) && !contains(wholeScriptTLDs[script], tld) {
+				return "whole-script"
+			}
+		}
+	}
+	return ""
+}
+
+func scriptOf(r rune) string {
+	for _, name := range recommendedScripts {
+		if unicode.Is(unicode.Scripts[name], r) {
+			return name
+		}
+	}
+	return ""
+}
+
+func digitZero(r rune) rune {
+	start := r
+	for unicode.IsDigit(start - 1) {
+		start--
+	}
+	return r - (r-start)%10
+}
+
+func allowedMix(scripts map[string]bool) bool {
+	if len(scripts) <= 1 {
+		return true
+	}
+	for _, set := range highlyRestrictive {
+		n := 0
+		for _, name := range set {
+			if scripts[name] {
+				n++
+			}
+		}
+		if n == len(scripts) {
+			return true
+		}
+	}
+	return false
+}
+
+// wholeScriptConfusable reports whether every non-ASCII letter of label has an ASCII lookalike.
+func wholeScriptConfusable(label string) bool {
+	for _, r := range label {
+		if r < 0x80 || unicode.In(r, unicode.Common, unicode.Inherited) {
+			continue
+		}
+		for _, c := range skeleton(string(r)) {
+			if c >= 0x80 || !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+// target is an input split around its public suffix. Only label is mutated;
+// subdomains are dropped since the registrable domain is what gets squatted.
+type target struct {
+	label  string
+	suffix string
+}
+
+// parseTarget splits a domain with the public suffix list. Input without a
+// dot is a bare word and has no suffix.
+func parseTarget(input string) (target, error) {
+	input = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(input)), ".")
+	if !strings.Contains(input, ".") {
+		return target{label: input}, nil
+	}
+	if u, err := idna.ToUnicode(input); err == nil {
+		input = u
+	}
+
+	suffix, _ := publicsuffix.PublicSuffix(input)
+	if suffix == input {
+		return target{}, fmt.Errorf("no registrable label before public suffix %s", suffix)
+	}
+	rest := strings.TrimSuffix(input, "."+suffix)
+	return target{label: rest[strings.LastIndex(rest, ".")+1:], suffix: suffix}, nil
+}
+
+func parseSuffixes(list string) []string {
+	var suffixes []string
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.Trim(strings.ToLower(strings.TrimSpace(s)), "."); s != "" {
+			suffixes = append(suffixes, s)
+		}
+	}
+	return suffixes
+}
+
+// suffixes returns the input's own suffix followed by the swapped ones.
+func (t target) suffixes(swap []string) []string {
+	if t.suffix == "" {
+		return []string{""}
+	}
+	result := []string{t.suffix}
+	for _, s := range swap {
+		if s != t.suffix {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func (t target) domain(label, suffix string) string {
+	if suffix == "" {
+		return label
+	}
+	return label + "." + suffix
+}
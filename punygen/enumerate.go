@@ -0,0 +1,176 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// candidates streams homoglyph substitutions of a label.
+type candidates interface {
+	next() (string, float64, bool)
+}
+
+type substitutions struct {
+	chars    []rune
+	slots    []int       // positions that have lookalikes
+	choices  [][]variant // lookalikes per position
+	maxEdits int
+	minScore float64
+}
+
+func newSubstitutions(chars []rune, opts options) substitutions {
+	s := substitutions{chars: chars, choices: make([][]variant, len(chars)), minScore: opts.minScore}
+	for i, char := range chars {
+		for _, v := range variantsFor(char) {
+			if opts.all || registrable(v.text) {
+				s.choices[i] = append(s.choices[i], v)
+			}
+		}
+		if len(s.choices[i]) > 0 {
+			s.slots = append(s.slots, i)
+		}
+	}
+	s.maxEdits = len(s.slots)
+	if opts.maxEdits > 0 && opts.maxEdits < s.maxEdits {
+		s.maxEdits = opts.maxEdits
+	}
+	return s
+}
+
+// build applies picks[i] to the position slots[combo[i]].
+func (s *substitutions) build(combo, picks []int) (string, float64) {
+	var b strings.Builder
+	score := 1.0
+	j := 0
+	for i, char := range s.chars {
+		if j < len(combo) && s.slots[combo[j]] == i {
+			v := s.choices[i][picks[j]]
+			b.WriteString(v.text)
+			score *= v.score
+			j++
+			continue
+		}
+		b.WriteRune(char)
+	}
+	return b.String(), score
+}
+
+// enumerator yields every substitution, all single edits first, then all
+// pairs, and so on, so a -m cap still covers every position.
+type enumerator struct {
+	substitutions
+	combo   []int
+	picks   []int
+	started bool
+	misses  int
+}
+
+// maxEnumerateMisses stops the walk after this many candidates in a row fell
+// below -s. Scores only shrink with more edits, so a long run of misses
+// means the rest of the space is mostly below the threshold as well.
+const maxEnumerateMisses = 100000
+
+func newEnumerator(chars []rune, opts options) *enumerator {
+	return &enumerator{substitutions: newSubstitutions(chars, opts)}
+}
+
+func (e *enumerator) next() (string, float64, bool) {
+	for e.misses < maxEnumerateMisses && e.advance() {
+		if text, score := e.build(e.combo, e.picks); score >= e.minScore {
+			e.misses = 0
+			return text, score, true
+		}
+		e.misses++
+	}
+	return "", 0, false
+}
+
+func (e *enumerator) advance() bool {
+	if !e.started {
+		e.started = true
+		return e.startTier(1)
+	}
+	for i := len(e.picks) - 1; i >= 0; i-- {
+		e.picks[i]++
+		if e.picks[i] < len(e.choices[e.slots[e.combo[i]]]) {
+			return true
+		}
+		e.picks[i] = 0
+	}
+	if nextCombination(e.combo, len(e.slots)) {
+		return true
+	}
+	return e.startTier(len(e.combo) + 1)
+}
+
+func (e *enumerator) startTier(k int) bool {
+	if k > e.maxEdits {
+		return false
+	}
+	e.combo = make([]int, k)
+	for i := range e.combo {
+		e.combo[i] = i
+	}
+	e.picks = make([]int, k)
+	return true
+}
+
+// nextCombination advances c to the next ascending k-subset of [0, n).
+func nextCombination(c []int, n int) bool {
+	i := len(c) - 1
+	for i >= 0 && c[i] == n-len(c)+i {
+		i--
+	}
+	if i < 0 {
+		return false
+	}
+	c[i]++
+	for j := i + 1; j < len(c); j++ {
+		c[j] = c[j-1] + 1
+	}
+	return true
+}
+
+// sampler draws substitutions at random: an edit count, then positions, then
+// lookalikes, each uniformly. It stops once draws keep repeating.
+type sampler struct {
+	substitutions
+	rng    *rand.Rand
+	seen   map[string]bool
+	misses int
+}
+
+const maxSampleMisses = 1000
+
+func newSampler(chars []rune, opts options) *sampler {
+	return &sampler{
+		substitutions: newSubstitutions(chars, opts),
+		rng:           rand.New(rand.NewSource(opts.seed)),
+		seen:          make(map[string]bool),
+	}
+}
+
+func (s *sampler) next() (string, float64, bool) {
+	if s.maxEdits == 0 {
+		return "", 0, false
+	}
+	for s.misses < maxSampleMisses {
+		combo := s.rng.Perm(len(s.slots))[:1+s.rng.Intn(s.maxEdits)]
+		sort.Ints(combo)
+		picks := make([]int, len(combo))
+		for i, slot := range combo {
+			picks[i] = s.rng.Intn(len(s.choices[s.slots[slot]]))
+		}
+
+		text, score := s.build(combo, picks)
+		if s.seen[text] || score < s.minScore {
+			s.misses++
+			continue
+		}
+		s.seen[text] = true
+		s.misses = 0
+		return text, score, true
+	}
+	return "", 0, false
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// sanKeys are the JSON fields certificate log dumps (certstream, crt.sh,
+// zgrab, ...) keep subject and alternative names in.
+var sanKeys = map[string]bool{
+	"all_domains": true, "dns_names": true, "dnsnames": true, "san": true, "sans": true,
+	"subject_alt_names": true, "subjectaltname": true, "name_value": true,
+	"common_name": true, "cn": true, "domain": true, "domains": true,
+}
+
+var recordTypes = map[string]bool{
+	"a": true, "aaaa": true, "cname": true, "ns": true, "mx": true, "txt": true,
+	"soa": true, "ds": true, "dnskey": true, "rrsig": true, "nsec": true, "nsec3": true,
+	"caa": true, "srv": true, "ptr": true, "https": true, "svcb": true,
+}
+
+type protected struct {
+	target
+	skeleton string
+}
+
+// matchFile streams a zone file, domain list or CT JSONL dump and prints every
+// entry whose registrable label has the same skeleton as a protected one.
+func matchFile(path string, domains []string, opts options) error {
+	var targets []protected
+	for _, d := range domains {
+		t, err := parseTarget(d)
+		if err != nil {
+			return fmt.Errorf("%s: %v", d, err)
+		}
+		if t.label != "" {
+			targets = append(targets, protected{t, skeleton(t.label)})
+		}
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("no domain to protect, use -i")
+	}
+
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	reported := make(map[string]bool)
+	check := func(name, kind string) {
+		name = strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), "."), "*.")
+		if name == "" || !strings.Contains(name, ".") {
+			return
+		}
+		t, err := parseTarget(name)
+		if err != nil || t.label == "" {
+			return
+		}
+		entry := t.domain(t.label, t.suffix)
+		if reported[entry] {
+			return
+		}
+		key := skeleton(t.label)
+		for _, p := range targets {
+			if key != p.skeleton || entry == p.domain(p.label, p.suffix) {
+				continue
+			}
+			reported[entry] = true
+			report(entry, kind, p.domain(p.label, p.suffix), opts)
+			return
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	origin := ""
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "", strings.HasPrefix(line, ";"), strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "{"):
+			var v interface{}
+			if json.Unmarshal([]byte(line), &v) == nil {
+				walkSANs(v, false, func(name string) { check(name, "ct") })
+			}
+		case strings.HasPrefix(line, "$ORIGIN"):
+			if fields := strings.Fields(line); len(fields) > 1 {
+				origin = strings.TrimSuffix(fields[1], ".")
+			}
+		case strings.HasPrefix(line, "$"):
+		default:
+			fields := strings.Fields(line)
+			if len(fields) == 1 {
+				check(fields[0], "list")
+				continue
+			}
+			if isZoneRecord(fields) {
+				check(zoneOwner(fields[0], origin), "zone")
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+func isZoneRecord(fields []string) bool {
+	for _, f := range fields[1:] {
+		if recordTypes[strings.ToLower(f)] {
+			return true
+		}
+	}
+	return false
+}
+
+// zoneOwner resolves an owner name relative to $ORIGIN.
+func zoneOwner(name, origin string) string {
+	switch {
+	case name == "@":
+		return origin
+	case strings.HasSuffix(name, "."), origin == "":
+		return name
+	}
+	return name + "." + origin
+}
+
+// walkSANs calls fn for every string under a SAN-like key. crt.sh packs
+// several names into one newline-separated name_value.
+func walkSANs(v interface{}, inSAN bool, fn func(string)) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			walkSANs(child, inSAN || sanKeys[strings.ToLower(k)], fn)
+		}
+	case []interface{}:
+		for _, child := range t {
+			walkSANs(child, inSAN, fn)
+		}
+	case string:
+		if inSAN {
+			for _, name := range strings.Fields(t) {
+				fn(name)
+			}
+		}
+	}
+}
+
+func report(domain, kind, target string, opts options) {
+	d := displayPolicy(domain)
+	if opts.unicodeOnly && !d.unicode {
+		return
+	}
+	p := toPunycode(domain)
+	if opts.verbose {
+		fmt.Printf("%s \033[32m→\033[0m %s [%s] %s matches %s\n", domain, p, kind, d, target)
+		return
+	}
+	fmt.Printf("%s %s %s\n", domain, p, target)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var strategyNames = []string{
+	"homoglyph", "bitsquat", "omission", "repetition", "transposition",
+	"keyboard", "vowel", "hyphenation", "subdomain", "dictionary",
+}
+
+var keyboardRows = []string{"1234567890-", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+var defaultDictionary = []string{
+	"login", "signin", "secure", "account", "verify", "auth", "support", "help",
+	"update", "billing", "pay", "wallet", "mail", "app", "online", "portal", "my", "web",
+}
+
+func parseStrategies(list string) ([]string, error) {
+	if list == "all" {
+		return strategyNames, nil
+	}
+	var strategies []string
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !contains(strategyNames, s) {
+			return nil, fmt.Errorf("unknown strategy %q (want %s or all)", s, strings.Join(strategyNames, ","))
+		}
+		strategies = append(strategies, s)
+	}
+	return strategies, nil
+}
+
+func readDictionary(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if w := strings.ToLower(strings.TrimSpace(scanner.Text())); w != "" && !strings.HasPrefix(w, "#") {
+			words = append(words, w)
+		}
+	}
+	return words, scanner.Err()
+}
+
+// emitter receives a mutated label and its similarity score (homoglyphs only,
+// -1 otherwise) and returns false once enough candidates were printed.
+type emitter func(label string, score float64) bool
+
+func generate(strategy, label string, opts options, emit emitter) {
+	chars := []rune(label)
+	switch strategy {
+	case "homoglyph":
+		homoglyphs(chars, opts, emit)
+	case "bitsquat":
+		bitsquat(chars, emit)
+	case "omission":
+		for i := range chars {
+			if !emit(string(chars[:i])+string(chars[i+1:]), -1) {
+				return
+			}
+		}
+	case "repetition":
+		for i := range chars {
+			if !emit(string(chars[:i+1])+string(chars[i:]), -1) {
+				return
+			}
+		}
+	case "transposition":
+		for i := 0; i+1 < len(chars); i++ {
+			swapped := append([]rune(nil), chars...)
+			swapped[i], swapped[i+1] = swapped[i+1], swapped[i]
+			if !emit(string(swapped), -1) {
+				return
+			}
+		}
+	case "keyboard":
+		replace(chars, keyboardNeighbours, emit)
+	case "vowel":
+		replace(chars, func(r rune) []rune {
+			if !strings.ContainsRune("aeiou", r) {
+				return nil
+			}
+			return []rune(strings.Replace("aeiou", string(r), "", 1))
+		}, emit)
+	case "hyphenation":
+		insert(chars, '-', emit)
+	case "subdomain":
+		insert(chars, '.', emit)
+	case "dictionary":
+		for _, word := range opts.dictionary {
+			for _, c := range []string{word + label, label + word, word + "-" + label, label + "-" + word} {
+				if !emit(c, -1) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func homoglyphs(chars []rune, opts options, emit emitter) {
+	var it candidates = newEnumerator(chars, opts)
+	if opts.random {
+		it = newSampler(chars, opts)
+	}
+	for {
+		text, score, ok := it.next()
+		if !ok || !emit(text, score) {
+			return
+		}
+	}
+}
+
+// bitsquat flips each bit of every ASCII character, keeping flips that are
+// still valid hostname characters.
+func bitsquat(chars []rune, emit emitter) {
+	for i, char := range chars {
+		if char >= 0x80 {
+			continue
+		}
+		for bit := 0; bit < 7; bit++ {
+			flipped := char ^ 1<<bit
+			if flipped >= 'A' && flipped <= 'Z' {
+				flipped += 'a' - 'A'
+			}
+			if !(flipped >= 'a' && flipped <= 'z' || flipped >= '0' && flipped <= '9' || flipped == '-') {
+				continue
+			}
+			if !emit(string(chars[:i])+string(flipped)+string(chars[i+1:]), -1) {
+				return
+			}
+		}
+	}
+}
+
+func replace(chars []rune, alternatives func(rune) []rune, emit emitter) {
+	for i, char := range chars {
+		for _, alt := range alternatives(char) {
+			if !emit(string(chars[:i])+string(alt)+string(chars[i+1:]), -1) {
+				return
+			}
+		}
+	}
+}
+
+// insert puts sep between characters, skipping spots next to an existing - or dot.
+func insert(chars []rune, sep rune, emit emitter) {
+	for i := 1; i < len(chars); i++ {
+		if strings.ContainsRune("-.", chars[i-1]) || strings.ContainsRune("-.", chars[i]) {
+			continue
+		}
+		if !emit(string(chars[:i])+string(sep)+string(chars[i:]), -1) {
+			return
+		}
+	}
+}
+
+// keyboardNeighbours returns the keys around r on a QWERTY layout.
+func keyboardNeighbours(r rune) []rune {
+	for row, keys := range keyboardRows {
+		col := strings.IndexRune(keys, r)
+		if col < 0 {
+			continue
+		}
+		var neighbours []rune
+		add := func(row, col int) {
+			if row >= 0 && row < len(keyboardRows) && col >= 0 && col < len(keyboardRows[row]) {
+				neighbours = append(neighbours, rune(keyboardRows[row][col]))
+			}
+		}
+		add(row, col-1)
+		add(row, col+1)
+		add(row-1, col)
+		add(row-1, col+1)
+		add(row+1, col)
+		add(row+1, col-1)
+		return neighbours
+	}
+	return nil
+}
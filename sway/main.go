@@ -2,31 +2,53 @@ package main
 
 import (
 	"bufio"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"text/tabwriter"
 	"time"
 
 	"github.com/fatih/color"
 	"github.com/sirupsen/logrus"
 )
 
+var methods = []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS", "HEAD"}
+
 type CustomFormatter struct{}
 
 func (f *CustomFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 	cyan := color.New(color.FgHiCyan, color.Bold)
+	magenta := color.New(color.FgHiMagenta, color.Bold)
 	grey := color.New(color.FgHiBlack)
 
+	if label, ok := entry.Data["finding"]; ok {
+		msg := magenta.Sprint("FIND") + " "
+		msg += grey.Sprint("target") + "=" + fmt.Sprintf("%v", entry.Data["target"])
+		msg += " " + grey.Sprint("finding") + "=" + fmt.Sprintf("%v", label)
+		msg += " " + grey.Sprint("method") + "=" + fmt.Sprintf("%v", entry.Data["method"])
+		msg += " " + grey.Sprint("detail") + "=" + fmt.Sprintf("\"%v\"", entry.Data["detail"])
+		return []byte(msg + "\n"), nil
+	}
+
 	msg := cyan.Sprint("INFO") + " "
 
-	if target, ok := entry.Data["target"];
-		ok {
+	if target, ok := entry.Data["target"]; ok {
 		msg += grey.Sprint("target") + "=" + fmt.Sprintf("%v", target)
 
-		methods := []string{"get", "post", "put", "delete", "patch", "options", "head"}
 		for _, method := range methods {
+			method = strings.ToLower(method)
 			if status, exists := entry.Data[method]; exists {
 				msg += " " + grey.Sprint(method) + "=" + fmt.Sprintf("\"%v\"", status)
 			}
@@ -36,20 +58,128 @@ func (f *CustomFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 	return []byte(msg + "\n"), nil
 }
 
+type probeResult struct {
+	Status    int    `json:"status"`
+	Length    int64  `json:"length"`
+	Location  string `json:"location,omitempty"`
+	Allow     string `json:"allow,omitempty"`
+	truncated bool
+	hash      string
+}
+
+type finding struct {
+	Label  string `json:"label"`
+	Method string `json:"method"`
+	Detail string `json:"detail"`
+}
+
+type row struct {
+	index    int
+	URL      string                  `json:"url"`
+	Methods  map[string]*probeResult `json:"methods"`
+	Findings []finding               `json:"findings,omitempty"`
+	dynamic  bool
+}
+
+type writer interface {
+	write(r *row)
+	flush()
+}
+
 func main() {
-	logrus.SetLevel(logrus.InfoLevel)
-	logrus.SetFormatter(&CustomFormatter{})
-	logrus.SetOutput(os.Stdout) // Change output to stdout
+	methodList := flag.String("m", strings.Join(methods, ","), "methods to probe (comma-separated)")
+	concurrency := flag.Int("c", 10, "number of urls to probe concurrently")
+	timeout := flag.Int("timeout", 3, "request timeout in seconds")
+	maxBody := flag.Int64("max-body", 1<<20, "max response body bytes to read per request")
+	format := flag.String("o", "log", "output format: log, table, csv, jsonl")
+	onlyFindings := flag.Bool("f", false, "only output urls with findings")
+	flag.Parse()
+
+	methods = parseMethods(*methodList)
+	if len(methods) == 0 {
+		fmt.Fprintln(os.Stderr, "error: no methods to probe")
+		os.Exit(1)
+	}
+
+	var out writer
+	switch *format {
+	case "log":
+		logrus.SetLevel(logrus.InfoLevel)
+		logrus.SetFormatter(&CustomFormatter{})
+		logrus.SetOutput(os.Stdout) // Change output to stdout
+		out = &logWriter{}
+	case "table":
+		out = &tableWriter{}
+	case "csv":
+		out = newCSVWriter()
+	case "jsonl":
+		out = &jsonWriter{}
+	default:
+		fmt.Fprintf(os.Stderr, "error: unknown output format %q\n", *format)
+		os.Exit(1)
+	}
+
+	if *concurrency < 1 {
+		*concurrency = 1
+	}
+
+	client := newClient(time.Duration(*timeout) * time.Second)
+
+	type job struct {
+		index int
+		url   string
+	}
+	jobs := make(chan job)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				r := test(client, j.url, *maxBody)
+				r.index = j.index
+				r.Findings = analyze(r)
+				if *onlyFindings && len(r.Findings) == 0 {
+					continue
+				}
+				mu.Lock()
+				out.write(r)
+				mu.Unlock()
+			}
+		}()
+	}
 
 	scanner := bufio.NewScanner(os.Stdin)
+	index := 0
 	for scanner.Scan() {
-		test(strings.TrimSpace(scanner.Text()))
+		if url := strings.TrimSpace(scanner.Text()); url != "" {
+			jobs <- job{index, url}
+			index++
+		}
 	}
+	close(jobs)
+
+	wg.Wait()
+	out.flush()
+}
+
+func parseMethods(list string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, m := range strings.Split(list, ",") {
+		m = strings.ToUpper(strings.TrimSpace(m))
+		if m != "" && !seen[m] {
+			seen[m] = true
+			result = append(result, m)
+		}
+	}
+	return result
 }
 
-func test(url string) {
-	methods := []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS", "HEAD"}
-	fields := logrus.Fields{"target": url}
+func test(client *http.Client, url string, maxBody int64) *row {
+	r := &row{URL: url, Methods: make(map[string]*probeResult)}
 
 	var wg sync.WaitGroup
 	var mu sync.Mutex
@@ -58,41 +188,261 @@ func test(url string) {
 		wg.Add(1)
 		go func(m string) {
 			defer wg.Done()
-			if status := probe(url, m); status > 0 {
+			if res := probe(client, url, m, maxBody); res != nil {
 				mu.Lock()
-				fields[strings.ToLower(m)] = status
+				r.Methods[m] = res
 				mu.Unlock()
 			}
 		}(method)
 	}
 
+	// a second GET tells pages with tokens or timestamps apart from
+	// methods that really return something else
+	var again *probeResult
+	for _, m := range methods {
+		if m == "GET" {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				again = probe(client, url, "GET", maxBody)
+			}()
+			break
+		}
+	}
+
 	wg.Wait()
 
-	if len(fields) > 1 {
-		logrus.WithFields(fields).Info("")
+	if get := r.Methods["GET"]; get != nil && again != nil {
+		r.dynamic = get.hash != again.hash
 	}
+	return r
 }
 
-func probe(url, method string) int {
-	client := &http.Client{
-		Timeout: 3 * time.Second,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
-	}
-
+func probe(client *http.Client, url, method string, maxBody int64) *probeResult {
 	req, err := http.NewRequest(method, url, nil)
 	if err != nil {
-		return 0
+		return nil
 	}
 
 	req.Header.Set("User-Agent", "sway/1.0")
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return 0
+		return nil
 	}
 	defer resp.Body.Close()
 
-	return resp.StatusCode
+	h := sha1.New()
+	n, _ := io.Copy(h, io.LimitReader(resp.Body, maxBody))
+	truncated := n == maxBody
+	if method == "HEAD" {
+		n = resp.ContentLength
+	}
+
+	return &probeResult{
+		Status:    resp.StatusCode,
+		Length:    n,
+		Location:  resp.Header.Get("Location"),
+		Allow:     resp.Header.Get("Allow"),
+		truncated: truncated,
+		hash:      hex.EncodeToString(h.Sum(nil)),
+	}
+}
+
+func analyze(r *row) []finding {
+	var findings []finding
+	add := func(label, method, format string, args ...interface{}) {
+		findings = append(findings, finding{label, method, fmt.Sprintf(format, args...)})
+	}
+
+	get := r.Methods["GET"]
+
+	for _, m := range methods {
+		res, ok := r.Methods[m]
+		if !ok || m == "GET" || m == "HEAD" || m == "OPTIONS" || get == nil {
+			continue
+		}
+		if isDenied(get.Status) && isSuccess(res.Status) {
+			add("method-bypass", m, "GET is %d but %s is %d", get.Status, m, res.Status)
+		}
+		if res.Status == 200 && get.Status == 200 && !r.dynamic && res.hash != get.hash && !res.truncated && !get.truncated {
+			add("body-differs", m, "%s returns 200 with %d bytes, GET returns %d bytes", m, res.Length, get.Length)
+		}
+	}
+
+	if head, ok := r.Methods["HEAD"]; ok && get != nil && isSuccess(head.Status) && isSuccess(get.Status) {
+		if head.Length >= 0 && !r.dynamic && !get.truncated && head.Length != get.Length {
+			add("head-length", "HEAD", "HEAD Content-Length is %d, GET body is %d bytes", head.Length, get.Length)
+		}
+	}
+
+	if opts, ok := r.Methods["OPTIONS"]; ok && opts.Allow != "" {
+		allowed := make(map[string]bool)
+		for _, m := range strings.Split(opts.Allow, ",") {
+			allowed[strings.ToUpper(strings.TrimSpace(m))] = true
+		}
+		// HEAD is implied by GET (RFC 9110) and rarely listed
+		if allowed["GET"] {
+			allowed["HEAD"] = true
+		}
+		for _, m := range methods {
+			res, ok := r.Methods[m]
+			if !ok || m == "OPTIONS" {
+				continue
+			}
+			switch {
+			case !allowed[m] && isSuccess(res.Status):
+				add("allow-mismatch", m, "%s is not in Allow (%s) but returns %d", m, opts.Allow, res.Status)
+			case allowed[m] && (res.Status == 405 || res.Status == 501):
+				add("allow-mismatch", m, "%s is in Allow (%s) but returns %d", m, opts.Allow, res.Status)
+			}
+		}
+	}
+
+	return findings
+}
+
+func isSuccess(status int) bool {
+	return status >= 200 && status < 300
+}
+
+func isDenied(status int) bool {
+	return status == 401 || status == 403
+}
+
+func newClient(timeout time.Duration) *http.Client {
+	tr := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		MaxIdleConns:        200,
+		MaxIdleConnsPerHost: len(methods),
+		IdleConnTimeout:     30 * time.Second,
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+		DialContext: (&net.Dialer{
+			Timeout:   timeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+	}
+
+	return &http.Client{
+		Transport: tr,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+type logWriter struct{}
+
+func (w *logWriter) write(r *row) {
+	if len(r.Methods) == 0 {
+		return
+	}
+	fields := logrus.Fields{"target": r.URL}
+	for m, res := range r.Methods {
+		fields[strings.ToLower(m)] = res.Status
+	}
+	logrus.WithFields(fields).Info("")
+
+	for _, f := range r.Findings {
+		logrus.WithFields(logrus.Fields{
+			"target":  r.URL,
+			"finding": f.Label,
+			"method":  f.Method,
+			"detail":  f.Detail,
+		}).Info("")
+	}
+}
+
+func (w *logWriter) flush() {}
+
+type jsonWriter struct{}
+
+func (w *jsonWriter) write(r *row) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	fmt.Println(string(b))
+}
+
+func (w *jsonWriter) flush() {}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter() *csvWriter {
+	w := csv.NewWriter(os.Stdout)
+	header := []string{"url"}
+	for _, m := range methods {
+		m = strings.ToLower(m)
+		header = append(header, m+"_status", m+"_length", m+"_location")
+	}
+	header = append(header, "findings")
+	w.Write(header)
+	return &csvWriter{w: w}
+}
+
+func (w *csvWriter) write(r *row) {
+	record := []string{r.URL}
+	for _, m := range methods {
+		if res, ok := r.Methods[m]; ok {
+			record = append(record, strconv.Itoa(res.Status), strconv.FormatInt(res.Length, 10), res.Location)
+		} else {
+			record = append(record, "", "", "")
+		}
+	}
+	var labels []string
+	for _, f := range r.Findings {
+		labels = append(labels, f.Label+":"+f.Method)
+	}
+	record = append(record, strings.Join(labels, ";"))
+	w.w.Write(record)
+	w.w.Flush()
+}
+
+func (w *csvWriter) flush() {
+	w.w.Flush()
+}
+
+type tableWriter struct {
+	rows []*row
+}
+
+func (w *tableWriter) write(r *row) {
+	w.rows = append(w.rows, r)
+}
+
+func (w *tableWriter) flush() {
+	rows := w.rows
+	sort.Slice(rows, func(i, j int) bool { return rows[i].index < rows[j].index })
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "URL\t%s\n", strings.Join(methods, "\t"))
+	for _, r := range rows {
+		cells := []string{r.URL}
+		for _, m := range methods {
+			cells = append(cells, cell(r.Methods[m]))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	tw.Flush()
+
+	for _, r := range rows {
+		for _, f := range r.Findings {
+			fmt.Printf("[%s] %s %s: %s\n", f.Label, r.URL, f.Method, f.Detail)
+		}
+	}
+}
+
+func cell(res *probeResult) string {
+	if res == nil {
+		return "-"
+	}
+	s := fmt.Sprintf("%d/%d", res.Status, res.Length)
+	if res.Location != "" {
+		s += " -> " + res.Location
+	}
+	return s
 }
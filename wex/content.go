@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	scriptRe    = regexp.MustCompile(`(?is)<script\b([^>]*)>(.*?)</script>`)
+	tagRe       = regexp.MustCompile(`(?is)<[a-z][a-z0-9-]*\b[^>]*>`)
+	attrRe      = regexp.MustCompile(`(?is)\s(id|name|for|data-[a-z0-9_.:-]+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+	scriptSrcRe = regexp.MustCompile(`(?i)\bsrc\s*=`)
+)
+
+var localExts = map[string]bool{
+	".js": true, ".mjs": true, ".cjs": true, ".jsx": true, ".ts": true, ".tsx": true,
+	".html": true, ".htm": true, ".json": true, ".map": true,
+}
+
+type wordSet map[string]int
+
+func (w wordSet) add(text string) {
+	for _, word := range wordRe.FindAllString(text, -1) {
+		if len(word) > 1 && !blacklist[strings.ToLower(word)] {
+			w[word]++
+		}
+	}
+}
+
+type sourceMap struct {
+	Sources        []string `json:"sources"`
+	SourcesContent []string `json:"sourcesContent"`
+	Mappings       *string  `json:"mappings"`
+}
+
+// detectKind picks an extractor from the content type, the file name and finally the body itself.
+func detectKind(contentType, name string, body []byte) string {
+	contentType = strings.ToLower(contentType)
+	ext := strings.ToLower(filepath.Ext(strings.SplitN(name, "?", 2)[0]))
+	trimmed := bytes.TrimSpace(body)
+
+	if ext == ".map" || strings.HasPrefix(string(trimmed), "{") && isSourceMap(trimmed) {
+		return "sourcemap"
+	}
+	switch {
+	case strings.Contains(contentType, "html"), ext == ".html", ext == ".htm":
+		return "html"
+	case strings.Contains(contentType, "json"), ext == ".json":
+		return "json"
+	case strings.Contains(contentType, "javascript"), strings.Contains(contentType, "ecmascript"), localExts[ext]:
+		return "js"
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return "html"
+	case (bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("["))) && json.Valid(trimmed):
+		return "json"
+	}
+	return "js"
+}
+
+func isSourceMap(body []byte) bool {
+	var sm sourceMap
+	return json.Unmarshal(body, &sm) == nil && sm.Mappings != nil && len(sm.Sources) > 0
+}
+
+func extractContent(kind string, body []byte, classes map[string]bool, words wordSet) {
+	switch kind {
+	case "html":
+		extractHTML(string(body), classes, words)
+	case "json":
+		extractJSON(body, words)
+	case "sourcemap":
+		extractSourceMap(body, classes, words)
+	default:
+		extractJS(string(body), classes, words)
+	}
+}
+
+func extractJS(content string, classes map[string]bool, words wordSet) {
+	classify(lex(content), func(class, text string) {
+		if classes[class] {
+			words.add(text)
+		}
+	})
+}
+
+// extractHTML collects form field names, ids and data attributes, and lexes inline scripts.
+func extractHTML(content string, classes map[string]bool, words wordSet) {
+	for _, m := range scriptRe.FindAllStringSubmatch(content, -1) {
+		if !scriptSrcRe.MatchString(m[1]) {
+			extractJS(m[2], classes, words)
+		}
+	}
+
+	for _, tag := range tagRe.FindAllString(scriptRe.ReplaceAllString(content, ""), -1) {
+		for _, m := range attrRe.FindAllStringSubmatch(tag, -1) {
+			attr := strings.ToLower(m[1])
+			value := m[2] + m[3] + m[4]
+			if strings.HasPrefix(attr, "data-") {
+				words.add(strings.TrimPrefix(attr, "data-"))
+				continue
+			}
+			words.add(value)
+		}
+	}
+}
+
+func extractJSON(body []byte, words wordSet) {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return
+	}
+	walkJSON(v, words)
+}
+
+func walkJSON(v interface{}, words wordSet) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			words.add(k)
+			walkJSON(child, words)
+		}
+	case []interface{}:
+		for _, child := range t {
+			walkJSON(child, words)
+		}
+	}
+}
+
+// extractSourceMap collects original file paths and lexes the embedded original sources.
+func extractSourceMap(body []byte, classes map[string]bool, words wordSet) {
+	var sm sourceMap
+	if err := json.Unmarshal(body, &sm); err != nil {
+		return
+	}
+	for _, src := range sm.Sources {
+		words.add(strings.TrimPrefix(src, "webpack://"))
+	}
+	for i, src := range sm.SourcesContent {
+		if i < len(sm.Sources) && detectKind("", sm.Sources[i], []byte(src)) == "html" {
+			extractHTML(src, classes, words)
+			continue
+		}
+		extractJS(src, classes, words)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+type headerList []string
+
+func (h *headerList) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerList) Set(value string) error {
+	if !strings.Contains(value, ":") {
+		return fmt.Errorf("header %q must be in \"Name: value\" form", value)
+	}
+	*h = append(*h, value)
+	return nil
+}
+
+type fetcher struct {
+	client  *http.Client
+	headers headerList
+	cookie  string
+	maxSize int64
+}
+
+func newFetcher(timeout time.Duration, headers headerList, cookie string, maxSize int64) *fetcher {
+	tr := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     30 * time.Second,
+		TLSHandshakeTimeout: timeout,
+		TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+		DialContext: (&net.Dialer{
+			Timeout:   timeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+	}
+	return &fetcher{
+		client:  &http.Client{Transport: tr, Timeout: timeout},
+		headers: headers,
+		cookie:  cookie,
+		maxSize: maxSize,
+	}
+}
+
+// fetch returns the decoded body and content type of a 200 response, capped at maxSize bytes.
+func (f *fetcher) fetch(target string) ([]byte, string, error) {
+	req, err := http.NewRequest("GET", target, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", "wex/1.0")
+	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+	for _, h := range f.headers {
+		name, value, _ := strings.Cut(h, ":")
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	if f.cookie != "" {
+		req.Header.Set("Cookie", f.cookie)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	body, err := decode(resp.Header.Get("Content-Encoding"), resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, f.maxSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > f.maxSize {
+		return data[:f.maxSize], resp.Header.Get("Content-Type"), errTruncated
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+var errTruncated = fmt.Errorf("response truncated")
+
+func decode(encoding string, body io.Reader) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(body)
+	case "br":
+		return brotli.NewReader(body), nil
+	case "deflate":
+		// servers disagree on whether deflate means zlib-wrapped or raw
+		br := bufio.NewReader(body)
+		if header, err := br.Peek(2); err == nil && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 && header[0]&0x0f == 8 {
+			return zlib.NewReader(br)
+		}
+		return flate.NewReader(br), nil
+	}
+	return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokNumber
+	tokString
+	tokTemplate
+	tokComment
+	tokRegex
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+var tokenClasses = []string{"ident", "prop", "key", "string", "template", "comment"}
+
+var regexKeywords = map[string]bool{
+	"return": true, "typeof": true, "case": true, "do": true, "else": true, "in": true, "instanceof": true,
+	"new": true, "delete": true, "void": true, "throw": true, "yield": true, "await": true,
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9')
+}
+
+func lex(src string) []token {
+	var tokens []token
+	var prev *token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			tokens = append(tokens, token{tokComment, src[i+2 : i+end]})
+			i += end
+			continue
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				tokens = append(tokens, token{tokComment, src[i+2:]})
+				i = len(src)
+			} else {
+				tokens = append(tokens, token{tokComment, src[i+2 : i+2+end]})
+				i += end + 4
+			}
+			continue
+		case c == '/' && regexAllowed(prev):
+			i = scanRegex(src, i)
+			tokens = append(tokens, token{tokRegex, ""})
+		case c == '"' || c == '\'':
+			var text string
+			text, i = scanString(src, i)
+			tokens = append(tokens, token{tokString, text})
+		case c == '`':
+			var text string
+			text, i = scanTemplate(src, i)
+			tokens = append(tokens, token{tokTemplate, text})
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && isIdentPart(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{tokIdent, src[start:i]})
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(src) && (isIdentPart(src[i]) || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, src[start:i]})
+		case strings.HasPrefix(src[i:], "..."):
+			tokens = append(tokens, token{tokPunct, "..."})
+			i += 3
+		case strings.HasPrefix(src[i:], "?."):
+			tokens = append(tokens, token{tokPunct, "?."})
+			i += 2
+		default:
+			tokens = append(tokens, token{tokPunct, src[i : i+1]})
+			i++
+		}
+		prev = &tokens[len(tokens)-1]
+	}
+	return tokens
+}
+
+// regexAllowed reports whether a '/' after prev starts a regex literal rather than a division.
+func regexAllowed(prev *token) bool {
+	if prev == nil {
+		return true
+	}
+	switch prev.kind {
+	case tokIdent:
+		return regexKeywords[prev.text]
+	case tokPunct:
+		return prev.text != ")" && prev.text != "]" && prev.text != "}"
+	default:
+		return false
+	}
+}
+
+func scanRegex(src string, i int) int {
+	inClass := false
+	for i++; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '\n':
+			return i
+		case '/':
+			if !inClass {
+				i++
+				for i < len(src) && isIdentPart(src[i]) {
+					i++
+				}
+				return i
+			}
+		}
+	}
+	return i
+}
+
+// scanString returns the literal's content with escape sequences blanked out.
+func scanString(src string, i int) (string, int) {
+	quote := src[i]
+	var b strings.Builder
+	for i++; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			b.WriteByte(' ')
+			i++
+		case quote:
+			return b.String(), i + 1
+		case '\n':
+			return b.String(), i
+		default:
+			b.WriteByte(src[i])
+		}
+	}
+	return b.String(), i
+}
+
+// scanTemplate returns a template literal's text, including its ${} expressions.
+func scanTemplate(src string, i int) (string, int) {
+	var b strings.Builder
+	for i++; i < len(src); i++ {
+		switch {
+		case src[i] == '\\':
+			b.WriteByte(' ')
+			i++
+		case src[i] == '`':
+			return b.String(), i + 1
+		case src[i] == '$' && i+1 < len(src) && src[i+1] == '{':
+			depth := 0
+			for i++; i < len(src); i++ {
+				if src[i] == '`' {
+					var inner string
+					inner, i = scanTemplate(src, i)
+					b.WriteString(" " + inner + " ")
+					i--
+					continue
+				}
+				if src[i] == '{' {
+					depth++
+				} else if src[i] == '}' {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+				b.WriteByte(src[i])
+			}
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(src[i])
+		}
+	}
+	return b.String(), i
+}
+
+// classify assigns each word-bearing token one of tokenClasses, using its neighbours
+// to tell member accesses and object keys apart from plain identifiers.
+func classify(tokens []token, fn func(class, text string)) {
+	var code []int
+	for i, t := range tokens {
+		if t.kind != tokComment {
+			code = append(code, i)
+		}
+	}
+	punctAt := func(j int, texts ...string) bool {
+		if j < 0 || j >= len(code) || tokens[code[j]].kind != tokPunct {
+			return false
+		}
+		for _, t := range texts {
+			if tokens[code[j]].text == t {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, t := range tokens {
+		if t.kind == tokComment {
+			fn("comment", t.text)
+		}
+	}
+
+	for j, idx := range code {
+		t := tokens[idx]
+		isKey := punctAt(j+1, ":") && (j == 0 || punctAt(j-1, "{", ","))
+		switch t.kind {
+		case tokIdent:
+			switch {
+			case punctAt(j-1, ".", "?."):
+				fn("prop", t.text)
+			case isKey:
+				fn("key", t.text)
+			default:
+				fn("ident", t.text)
+			}
+		case tokString:
+			if isKey {
+				fn("key", t.text)
+			} else {
+				fn("string", t.text)
+			}
+		case tokTemplate:
+			fn("template", t.text)
+		}
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
-	"io"
-	"net/http"
+	"io/fs"
 	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 )
 
@@ -17,68 +19,174 @@ var blacklist = map[string]bool{
 	"await": true, "break": true, "case": true, "catch": true, "class": true, "const": true, "continue": true, "debugger": true, "default": true, "delete": true, "do": true, "else": true, "enum": true, "export": true, "extends": true, "false": true, "finally": true, "for": true, "function": true, "if": true, "implements": true, "import": true, "in": true, "instanceof": true, "interface": true, "let": true, "new": true, "null": true, "package": true, "private": true, "protected": true, "public": true, "return": true, "super": true, "switch": true, "static": true, "this": true, "throw": true, "try": true, "true": true, "typeof": true, "var": true, "void": true, "while": true, "with": true, "abstract": true, "boolean": true, "byte": true, "char": true, "double": true, "final": true, "float": true, "goto": true, "int": true, "long": true, "native": true, "short": true, "synchronized": true, "throws": true, "transient": true, "volatile": true, "window": true, "document": true, "undefined": true, "eval": true, "alert": true, "console": true,
 }
 
+var wordRe = regexp.MustCompile(`[a-zA-Z_][a-zA-Z0-9_]*`)
+
 func main() {
-	js := flag.Bool("js", false, "extract js keywords (supported only for .js files)")
+	js := flag.Bool("js", false, "extract keywords from fetched js, html, json and source maps")
+	local := flag.Bool("local", false, "extract keywords from local files and directories")
 	url := flag.Bool("url", false, "extract path/parameter from urls")
+	classList := flag.String("classes", "ident,prop,key,string,template", "js token classes to extract ("+strings.Join(tokenClasses, ",")+")")
+	transformList := flag.String("transform", "", "add word variants ("+strings.Join(transformNames, ",")+")")
+	counts := flag.Bool("counts", false, "prefix each word with its frequency")
+	merge := flag.String("merge", "", "fold words into this wordlist file, keeping its format (counts or plain)")
+	concurrency := flag.Int("c", 10, "number of concurrent fetches")
+	timeout := flag.Int("timeout", 10, "request timeout in seconds")
+	maxSize := flag.Int64("max-size", 10<<20, "max decoded response size in bytes")
+	cookie := flag.String("cookie", "", "cookie header to send with -js requests")
+	var headers headerList
+	flag.Var(&headers, "H", "header to send with -js requests, \"Name: value\" (repeatable)")
 	flag.Parse()
-	
+
+	classes, err := parseClasses(*classList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	transforms, err := parseTransforms(*transformList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *concurrency < 1 {
+		*concurrency = 1
+	}
+	f := newFetcher(time.Duration(*timeout)*time.Second, headers, *cookie, *maxSize)
+
+	words := make(wordSet)
+	inputs := make(chan string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for input := range inputs {
+				found := make(wordSet)
+				process(input, f, *js, *local, *url, classes, found)
+				mu.Lock()
+				for word, count := range found {
+					words[word] += count
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
 	scanner := bufio.NewScanner(os.Stdin)
 	if flag.NArg() > 0 {
 		for _, arg := range flag.Args() {
-			process(arg, *js, *url)
+			inputs <- arg
 		}
 	} else {
 		for scanner.Scan() {
 			if line := strings.TrimSpace(scanner.Text()); line != "" {
-				process(line, *js, *url)
+				inputs <- line
+			}
+		}
+	}
+	close(inputs)
+	wg.Wait()
+
+	applyTransforms(words, transforms)
+
+	if *merge != "" {
+		if err := mergeWordlist(*merge, words, *counts); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	writeWords(os.Stdout, words, *counts)
+}
+
+func parseClasses(list string) (map[string]bool, error) {
+	classes := make(map[string]bool)
+	for _, class := range strings.Split(list, ",") {
+		class = strings.TrimSpace(class)
+		if class == "" {
+			continue
+		}
+		if class == "all" {
+			for _, c := range tokenClasses {
+				classes[c] = true
 			}
+			continue
+		}
+		known := false
+		for _, c := range tokenClasses {
+			known = known || c == class
 		}
+		if !known {
+			return nil, fmt.Errorf("unknown token class %q (want %s)", class, strings.Join(tokenClasses, ","))
+		}
+		classes[class] = true
 	}
+	return classes, nil
 }
 
-func process(input string, js, url bool) {
+func process(input string, f *fetcher, js, local, url bool, classes map[string]bool, words wordSet) {
 	if js {
-		if resp, err := http.Get(input); err == nil && resp.StatusCode == 200 {
-			if body, err := io.ReadAll(resp.Body); err == nil {
-				extract(string(body))
+		body, contentType, err := f.fetch(input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s: %v\n", input, err)
+			if err != errTruncated {
+				return
 			}
-			resp.Body.Close()
 		}
+		extractContent(detectKind(contentType, input, body), body, classes, words)
+	} else if local {
+		extractLocal(input, classes, words)
 	} else if url {
-		extractURL(input)
+		extractURL(input, words)
 	}
 }
 
-func extract(content string) {
-	words := make(map[string]bool)
-	re := regexp.MustCompile(`[a-zA-Z_][a-zA-Z0-9_]*`)
-	for _, word := range re.FindAllString(content, -1) {
-		if len(word) > 1 && !blacklist[strings.ToLower(word)] {
-			words[word] = true
+func extractLocal(path string, classes map[string]bool, words wordSet) {
+	readFile := func(name string) {
+		body, err := os.ReadFile(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return
 		}
+		extractContent(detectKind("", name, body), body, classes, words)
 	}
-	for word := range words {
-		fmt.Println(word)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return
+	}
+	if !info.IsDir() {
+		readFile(path)
+	} else {
+		filepath.WalkDir(path, func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return nil
+			}
+			if !d.IsDir() && localExts[strings.ToLower(filepath.Ext(name))] {
+				readFile(name)
+			}
+			return nil
+		})
 	}
 }
 
-func extractURL(urlStr string) {
+func extractURL(urlStr string, words wordSet) {
 	if u, err := url.Parse(urlStr); err == nil {
-		words := make(map[string]bool)
 		text := u.Path + "?" + u.RawQuery
 		for _, part := range regexp.MustCompile(`[^/\?&=\-_.]+`).FindAllString(text, -1) {
 			if len(part) > 0 && !blacklist[strings.ToLower(part)] {
-				words[part] = true
+				words[part]++
 				for _, camel := range splitCamel(part) {
-					if len(camel) > 0 && !blacklist[strings.ToLower(camel)] {
-						words[camel] = true
+					if len(camel) > 0 && camel != part && !blacklist[strings.ToLower(camel)] {
+						words[camel]++
 					}
 				}
 			}
 		}
-		for word := range words {
-			fmt.Println(word)
-		}
 	}
 }
 
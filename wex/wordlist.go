@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var transformNames = []string{"snake", "kebab", "camel", "lower", "singular", "plural"}
+
+func parseTransforms(list string) ([]string, error) {
+	var transforms []string
+	for _, t := range strings.Split(list, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		known := false
+		for _, name := range transformNames {
+			known = known || name == t
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown transform %q (want %s)", t, strings.Join(transformNames, ","))
+		}
+		transforms = append(transforms, t)
+	}
+	return transforms, nil
+}
+
+// wordParts splits a word on underscores, dashes and camel case into lowercase parts.
+func wordParts(word string) []string {
+	var parts []string
+	for _, chunk := range strings.FieldsFunc(word, func(r rune) bool { return r == '_' || r == '-' }) {
+		for _, part := range splitCamel(chunk) {
+			parts = append(parts, strings.ToLower(part))
+		}
+	}
+	return parts
+}
+
+func transform(word, name string) string {
+	parts := wordParts(word)
+	if len(parts) == 0 {
+		return word
+	}
+	switch name {
+	case "snake":
+		return strings.Join(parts, "_")
+	case "kebab":
+		return strings.Join(parts, "-")
+	case "camel":
+		for i := 1; i < len(parts); i++ {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+		return strings.Join(parts, "")
+	case "lower":
+		return strings.ToLower(word)
+	case "singular":
+		return replaceLast(word, singular)
+	case "plural":
+		return replaceLast(word, plural)
+	}
+	return word
+}
+
+// replaceLast applies fn to the trailing letters of word, so userIds becomes userId.
+func replaceLast(word string, fn func(string) string) string {
+	end := len(word)
+	start := end
+	for start > 0 {
+		c := word[start-1]
+		if c == '_' || c == '-' || (c >= '0' && c <= '9') {
+			break
+		}
+		start--
+		if c >= 'A' && c <= 'Z' && (start == 0 || word[start-1] < 'A' || word[start-1] > 'Z') {
+			break
+		}
+	}
+	if start == end {
+		return word
+	}
+	return word[:start] + fn(word[start:end])
+}
+
+func singular(s string) string {
+	lower := strings.ToLower(s)
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(s) > 3:
+		return s[:len(s)-3] + "y"
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "xes"), strings.HasSuffix(lower, "ches"), strings.HasSuffix(lower, "shes"):
+		return s[:len(s)-2]
+	case strings.HasSuffix(lower, "ss"), strings.HasSuffix(lower, "us"), strings.HasSuffix(lower, "is"):
+		return s
+	case strings.HasSuffix(lower, "s") && len(s) > 2:
+		return s[:len(s)-1]
+	}
+	return s
+}
+
+func plural(s string) string {
+	lower := strings.ToLower(s)
+	switch {
+	case singular(s) != s:
+		return s
+	case strings.HasSuffix(lower, "y") && len(s) > 1 && !strings.ContainsAny(lower[len(lower)-2:len(lower)-1], "aeiou"):
+		return s[:len(s)-1] + "ies"
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return s + "es"
+	}
+	return s + "s"
+}
+
+func applyTransforms(words wordSet, transforms []string) {
+	if len(transforms) == 0 {
+		return
+	}
+	variants := make(wordSet)
+	for word, count := range words {
+		for _, t := range transforms {
+			if v := transform(word, t); v != word {
+				variants[v] += count
+			}
+		}
+	}
+	for word, count := range variants {
+		words[word] += count
+	}
+}
+
+// readWordlist loads a wordlist written by -counts (count, tab, word) or a
+// plain one-word-per-line list, and reports whether it had counts.
+func readWordlist(r io.Reader, words wordSet) (bool, error) {
+	counted := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if count, word, ok := strings.Cut(line, "\t"); ok {
+			if n, err := strconv.Atoi(count); err == nil {
+				words[word] += n
+				counted = true
+				continue
+			}
+		}
+		words[line]++
+	}
+	return counted, scanner.Err()
+}
+
+// mergeWordlist folds words into the list at path, keeping its format: counts
+// stay counts and a plain list stays plain for fuzzers. A new list gets counts
+// if counts is set. The list is replaced by a rename, so a failed write leaves
+// it intact.
+func mergeWordlist(path string, words wordSet, counts bool) error {
+	mode := os.FileMode(0644)
+	if f, err := os.Open(path); err == nil {
+		info, err := f.Stat()
+		if err == nil {
+			mode = info.Mode().Perm()
+			var counted bool
+			if counted, err = readWordlist(f, words); err == nil && info.Size() > 0 {
+				counts = counted
+			}
+		}
+		f.Close()
+		if err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	writeWords(w, words, counts)
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func rank(words wordSet) []string {
+	ranked := make([]string, 0, len(words))
+	for word := range words {
+		ranked = append(ranked, word)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if words[ranked[i]] != words[ranked[j]] {
+			return words[ranked[i]] > words[ranked[j]]
+		}
+		return ranked[i] < ranked[j]
+	})
+	return ranked
+}
+
+func writeWords(w io.Writer, words wordSet, counts bool) {
+	for _, word := range rank(words) {
+		if counts {
+			fmt.Fprintf(w, "%d\t%s\n", words[word], word)
+		} else {
+			fmt.Fprintln(w, word)
+		}
+	}
+}